@@ -0,0 +1,163 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mattfan00/jvbe/auditlog"
+)
+
+type accountExport struct {
+	ExportedAt time.Time               `json:"exportedAt"`
+	Profile    accountExportProfile    `json:"profile"`
+	Groups     []accountExportGroup    `json:"groups"`
+	Responses  []accountExportResponse `json:"responses"`
+	AuditLog   []accountExportAuditLog `json:"auditLog"`
+}
+
+type accountExportProfile struct {
+	Id                string    `json:"id"`
+	FullName          string    `json:"fullName"`
+	ExternalFullName  string    `json:"externalFullName"`
+	Picture           string    `json:"picture"`
+	Bio               string    `json:"bio"`
+	ContactInfo       string    `json:"contactInfo"`
+	ContactVisibility int       `json:"contactVisibility"`
+	Timezone          string    `json:"timezone"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+type accountExportGroup struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type accountExportResponse struct {
+	EventId       string    `json:"eventId"`
+	EventName     string    `json:"eventName"`
+	EventStart    time.Time `json:"eventStart"`
+	AttendeeCount int       `json:"attendeeCount"`
+	OnWaitlist    bool      `json:"onWaitlist"`
+	Cancelled     bool      `json:"cancelled"`
+}
+
+type accountExportAuditLog struct {
+	RecordedAt  time.Time `json:"recordedAt"`
+	Description string    `json:"description"`
+}
+
+func (a *App) exportAccount() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		su, _ := a.sessionUser(r)
+
+		u, err := a.userService.Get(su.Id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		groups, err := a.groupService.ListForUser(u.Id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responses, err := a.eventService.ListUserResponses(u.Id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		entries, _, err := a.auditlogService.List(auditlog.ListFilter{UserId: u.Id})
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		export := accountExport{
+			ExportedAt: time.Now().UTC(),
+			Profile: accountExportProfile{
+				Id:                u.Id,
+				FullName:          u.FullName,
+				ExternalFullName:  u.ExternalFullName,
+				Picture:           u.Picture,
+				Bio:               u.Bio,
+				ContactInfo:       u.ContactInfo,
+				ContactVisibility: int(u.ContactVisibility),
+				Timezone:          u.Timezone,
+				CreatedAt:         u.CreatedAt,
+			},
+			Groups:    []accountExportGroup{},
+			Responses: []accountExportResponse{},
+			AuditLog:  []accountExportAuditLog{},
+		}
+		for _, g := range groups {
+			export.Groups = append(export.Groups, accountExportGroup{Id: g.Id, Name: g.Name})
+		}
+		for _, resp := range responses {
+			export.Responses = append(export.Responses, accountExportResponse{
+				EventId:       resp.EventId,
+				EventName:     resp.EventName,
+				EventStart:    resp.EventStart,
+				AttendeeCount: resp.AttendeeCount,
+				OnWaitlist:    resp.OnWaitlist,
+				Cancelled:     resp.IsCancelled(),
+			})
+		}
+		for _, e := range entries {
+			export.AuditLog = append(export.AuditLog, accountExportAuditLog{RecordedAt: e.RecordedAt, Description: e.Description})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"jvbe-%s.json\"", u.Id))
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(export); err != nil {
+			a.log.Errorf(err.Error())
+		}
+	}
+}
+
+func (a *App) deleteAccount() http.HandlerFunc {
+	type request struct {
+		Confirm string `schema:"confirm"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		su, _ := a.sessionUser(r)
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+		if req.Confirm != "delete" {
+			a.renderErrorNotif(w, fmt.Errorf("type \"delete\" to confirm"), http.StatusBadRequest)
+			return
+		}
+
+		withdrawn, err := a.userService.Delete(su.Id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+		for _, wr := range withdrawn {
+			a.notifyWaitlistChanges(su.Id, wr.EventId, wr.EventName, wr.Moved)
+		}
+
+		err = a.auditlogService.Create(su.Id, "Deleted their account")
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+
+		err = a.session.Destroy(r.Context())
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
@@ -2,26 +2,39 @@ package app
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/mattfan00/jvbe/app/template"
 	"github.com/mattfan00/jvbe/auditlog"
 	"github.com/mattfan00/jvbe/auth"
+	"github.com/mattfan00/jvbe/comment"
 	"github.com/mattfan00/jvbe/config"
 	"github.com/mattfan00/jvbe/event"
 	"github.com/mattfan00/jvbe/group"
+	"github.com/mattfan00/jvbe/ledger"
 	"github.com/mattfan00/jvbe/logger"
+	"github.com/mattfan00/jvbe/notification"
+	"github.com/mattfan00/jvbe/poll"
+	"github.com/mattfan00/jvbe/team"
 	"github.com/mattfan00/jvbe/user"
+	"github.com/mattfan00/jvbe/venue"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/gorilla/schema"
 )
 
 type App struct {
-	eventService    event.Service
-	userService     user.Service
-	authService     auth.Service
-	groupService    group.Service
-	auditlogService auditlog.Service
+	eventService        event.Service
+	userService         user.Service
+	authService         auth.Service
+	groupService        group.Service
+	auditlogService     auditlog.Service
+	commentService      comment.Service
+	notificationService notification.Service
+	venueService        venue.Service
+	pollService         poll.Service
+	ledgerService       ledger.Service
+	teamService         team.Service
 
 	conf            *config.Config
 	session         *scs.SessionManager
@@ -35,6 +48,12 @@ func New(
 	authService auth.Service,
 	groupService group.Service,
 	auditlogService auditlog.Service,
+	commentService comment.Service,
+	notificationService notification.Service,
+	venueService venue.Service,
+	pollService poll.Service,
+	ledgerService ledger.Service,
+	teamService team.Service,
 
 	conf *config.Config,
 	session *scs.SessionManager,
@@ -43,11 +62,17 @@ func New(
 	templateManager := template.NewManager(log)
 
 	return &App{
-		eventService:    eventService,
-		userService:     userService,
-		authService:     authService,
-		groupService:    groupService,
-		auditlogService: auditlogService,
+		eventService:        eventService,
+		userService:         userService,
+		authService:         authService,
+		groupService:        groupService,
+		auditlogService:     auditlogService,
+		commentService:      commentService,
+		notificationService: notificationService,
+		venueService:        venueService,
+		pollService:         pollService,
+		ledgerService:       ledgerService,
+		teamService:         teamService,
 
 		conf:            conf,
 		session:         session,
@@ -60,6 +85,10 @@ type BaseData struct {
 	User user.SessionUser
 }
 
+func (b BaseData) viewer() user.SessionUser {
+	return b.User
+}
+
 func (a *App) renderPage(w http.ResponseWriter, pageFile string, data any) {
 	files := []string{"base.html", "header.html"}
 	files = append(files, pageFile)
@@ -69,6 +98,17 @@ func (a *App) renderPage(w http.ResponseWriter, pageFile string, data any) {
 		return
 	}
 
+	// times are shown in the timezone of the user viewing the page
+	var viewer user.SessionUser
+	if d, ok := data.(interface{ viewer() user.SessionUser }); ok {
+		viewer = d.viewer()
+	}
+	t, err = template.InLocation(t, a.location(viewer))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	t.Execute(w, data)
 }
 
@@ -79,6 +119,12 @@ func (a *App) renderTemplate(w http.ResponseWriter, templateFile string, data an
 		return
 	}
 
+	t, err = template.InLocation(t, time.UTC)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	t.Execute(w, data)
 }
 
@@ -115,7 +161,7 @@ func (a *App) renewSessionUser(r *http.Request, u *user.SessionUser) error {
 		return err
 	}
 
-	a.session.Put(r.Context(), "user", u)
+	a.session.Put(r.Context(), "user", *u)
 
 	return nil
 }
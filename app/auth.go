@@ -1,27 +1,81 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 
+	"github.com/go-chi/chi/v5"
 	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/mattfan00/jvbe/auth"
+	"github.com/mattfan00/jvbe/user"
 )
 
+// Lets the user pick the provider to log in with, going straight to it if there is only one.
 func (a *App) renderLogin() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Providers []auth.Provider
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		providers := a.authService.Providers()
+		if len(providers) == 1 {
+			http.Redirect(w, r, "/auth/login/"+providers[0].Id, http.StatusSeeOther)
+			return
+		}
+
+		a.renderPage(w, "login.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Providers: providers,
+		})
+	}
+}
+
+func (a *App) redirectToProvider() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		providerId := chi.URLParam(r, "provider")
+
 		state, err := gonanoid.New()
 		if err != nil {
 			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		// linking another identity needs a fresh login rather than the session the user is already in
+		forceLogin := a.session.GetBool(r.Context(), "link")
+		url, err := a.authService.AuthCodeUrl(providerId, state, forceLogin)
+		if errors.Is(err, auth.ErrNoProvider) {
+			a.renderErrorPage(w, err, http.StatusNotFound)
+			return
+		} else if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
 		}
 
 		a.session.Put(r.Context(), "state", state)
+		a.session.Put(r.Context(), "loginProvider", providerId)
 
-		url := a.authService.AuthCodeUrl(state)
 		http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 	}
 }
 
+// Logs in again with another identity that is linked to the current user on callback.
+func (a *App) linkIdentity() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.session.Put(r.Context(), "link", true)
+		http.Redirect(w, r, "/auth/login", http.StatusSeeOther)
+	}
+}
+
 func (a *App) handleLoginCallback() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("login callback: %s", r.URL.String())
@@ -34,14 +88,46 @@ func (a *App) handleLoginCallback() http.HandlerFunc {
 			return
 		}
 
+		providerId := a.session.PopString(r.Context(), "loginProvider")
 		code := r.URL.Query().Get("code")
-		eu, err := a.authService.GetExternalUser(code)
-		if err != nil {
+		eu, idToken, err := a.authService.GetExternalUser(providerId, code)
+		if errors.Is(err, auth.ErrNoProvider) {
+			a.renderErrorPage(w, err, http.StatusBadRequest)
+			return
+		} else if err != nil {
 			a.renderErrorPage(w, err, http.StatusInternalServerError)
 			return
 		}
 
-		u, err := a.userService.HandleFromExternal(eu)
+		if a.session.PopBool(r.Context(), "link") {
+			su, ok := a.sessionUser(r)
+			if !ok {
+				http.Redirect(w, r, "/", http.StatusSeeOther)
+				return
+			}
+
+			err = a.userService.LinkIdentity(su.Id, eu)
+			if errors.Is(err, user.ErrIdentityInUse) {
+				a.renderErrorPage(w, err, http.StatusBadRequest)
+				return
+			} else if err != nil {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
+				return
+			}
+
+			http.Redirect(w, r, "/user/profile/edit", http.StatusSeeOther)
+			return
+		}
+
+		u, err := a.userService.HandleFromExternal(eu, user.Signup{
+			InviteId:      a.session.PopString(r.Context(), "invite"),
+			ReferralToken: a.session.PopString(r.Context(), "referral"),
+			Rules: user.AutoApproveRules{
+				InviteGroupIds: a.conf.AutoApprove.InviteGroupIds,
+				EmailDomains:   a.conf.AutoApprove.EmailDomains,
+				Vouch:          a.conf.AutoApprove.Vouch,
+			},
+		})
 		if err != nil {
 			a.renderErrorPage(w, err, http.StatusInternalServerError)
 			return
@@ -55,6 +141,9 @@ func (a *App) handleLoginCallback() http.HandlerFunc {
 			a.renderErrorPage(w, err, http.StatusInternalServerError)
 			return
 		}
+		// used to log out of the provider as well
+		a.session.Put(r.Context(), "provider", providerId)
+		a.session.Put(r.Context(), "idToken", idToken)
 
 		redirect := a.session.PopString(r.Context(), "redirect")
 		if redirect != "" {
@@ -65,15 +154,149 @@ func (a *App) handleLoginCallback() http.HandlerFunc {
 	}
 }
 
+// Stands in for the IdP login page when dev_auth is enabled.
+func (a *App) renderDevLogin() http.HandlerFunc {
+	type data struct {
+		BaseData
+		State       string
+		Users       []user.User
+		Permissions []string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		users, err := a.userService.List()
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "dev_login.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			State:       r.URL.Query().Get("state"),
+			Users:       users,
+			Permissions: auth.DevPermissions,
+		})
+	}
+}
+
+func (a *App) handleDevLogin() http.HandlerFunc {
+	type request struct {
+		State       string   `schema:"state"`
+		UserId      string   `schema:"userId"`
+		FullName    string   `schema:"fullName"`
+		Approve     bool     `schema:"approve"`
+		Permissions []string `schema:"permissions"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		var u user.User
+		var subject string
+		if req.UserId != "" {
+			u, err = a.userService.Get(req.UserId)
+			if errors.Is(err, user.ErrNoUser) {
+				a.renderErrorPage(w, err, http.StatusBadRequest)
+				return
+			} else if err != nil {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
+				return
+			}
+
+			// the user logs in as themselves from now on, whatever IdP they signed up with
+			subject = u.Id
+			err = a.userService.LinkIdentity(u.Id, user.ExternalUser{Issuer: auth.DevIssuer, Id: subject})
+			if err != nil {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
+				return
+			}
+		} else {
+			if strings.TrimSpace(req.FullName) == "" {
+				a.renderErrorPage(w, errors.New("pick a user or enter a name"), http.StatusBadRequest)
+				return
+			}
+
+			subject, err = gonanoid.New()
+			if err != nil {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
+				return
+			}
+
+			u, err = a.userService.Create(user.CreateParams{
+				Issuer:     auth.DevIssuer,
+				ExternalId: subject,
+				FullName:   strings.TrimSpace(req.FullName),
+			})
+			if err != nil {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
+				return
+			}
+
+			if req.Approve {
+				err = a.userService.ApproveReview(user.ReviewParams{UserId: u.Id})
+				if err != nil {
+					a.renderErrorPage(w, err, http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+
+		permissions := []string{}
+		for _, p := range req.Permissions {
+			if slices.Contains(auth.DevPermissions, p) {
+				permissions = append(permissions, p)
+			}
+		}
+
+		code, err := auth.DevLoginCode(subject, u.FullName, permissions)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		q := url.Values{}
+		q.Set("state", req.State)
+		q.Set("code", code)
+		http.Redirect(w, r, "/auth/callback?"+q.Encode(), http.StatusSeeOther)
+	}
+}
+
+// Referral link of a member, new users signing up through it count as vouched for.
+func (a *App) handleReferral() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.session.Put(r.Context(), "referral", chi.URLParam(r, "token"))
+		http.Redirect(w, r, "/auth/login", http.StatusSeeOther)
+	}
+}
+
 func (a *App) handleLogout() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		providerId := a.session.GetString(r.Context(), "provider")
+		idToken := a.session.GetString(r.Context(), "idToken")
+
 		err := a.session.Destroy(r.Context())
 		if err != nil {
 			a.renderErrorPage(w, err, http.StatusInternalServerError)
 			return
 		}
 
-		redirect := fmt.Sprintf("https://%s/logout?redirect=%s", a.conf.Oauth.Domain, a.conf.OauthLogoutRedirectUrl())
+		redirect, err := a.authService.LogoutUrl(providerId, idToken, a.conf.OauthLogoutRedirectUrl())
+		if errors.Is(err, auth.ErrNoProvider) {
+			// logged in before the provider was recorded, or the provider has since been removed
+			redirect = a.conf.OauthLogoutRedirectUrl()
+		} else if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
 		http.Redirect(w, r, redirect, http.StatusSeeOther)
 	}
 }
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mattfan00/jvbe/comment"
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/notification"
+	"github.com/mattfan00/jvbe/user"
+)
+
+// Organizers can moderate the discussion of any event
+func canModerateComments(u user.SessionUser, e event.Event) bool {
+	return u.CanModifyEvent() || e.CreatorId == u.Id
+}
+
+func (a *App) createComment() http.HandlerFunc {
+	type request struct {
+		Body           string   `schema:"body"`
+		MentionUserIds []string `schema:"mentionUserIds"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		e, err := a.eventService.Get(id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err = a.groupService.UserCanAccessError(e.GroupId, u.Id); err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		c, err := a.commentService.Create(comment.CreateParams{
+			EventId:        id,
+			UserId:         u.Id,
+			Body:           req.Body,
+			MentionUserIds: req.MentionUserIds,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		mentionUserIds := []string{}
+		for _, m := range c.Mentions {
+			mentionUserIds = append(mentionUserIds, m.UserId)
+		}
+		err = a.notificationService.Create(notification.CreateParams{
+			UserIds: mentionUserIds,
+			Message: fmt.Sprintf("%s mentioned you in a comment on %s", u.FullName, e.Name),
+			Link:    "/event/" + id,
+		})
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+
+		http.Redirect(w, r, "/event/"+id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) updateComment() http.HandlerFunc {
+	type request struct {
+		Body string `schema:"body"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+		commentId := chi.URLParam(r, "commentId")
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		c, err := a.commentService.Get(commentId)
+		if errors.Is(err, comment.ErrNoComment) {
+			a.renderErrorNotif(w, err, http.StatusNotFound)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+		if c.EventId != id {
+			a.renderErrorNotif(w, comment.ErrNoComment, http.StatusNotFound)
+			return
+		}
+
+		err = a.commentService.Update(comment.UpdateParams{
+			Id:     commentId,
+			UserId: u.Id,
+			Body:   req.Body,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/event/"+id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) deleteComment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+		commentId := chi.URLParam(r, "commentId")
+
+		e, err := a.eventService.Get(id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		c, err := a.commentService.Get(commentId)
+		if errors.Is(err, comment.ErrNoComment) {
+			a.renderErrorNotif(w, err, http.StatusNotFound)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+		// moderation rights come from the event in the url, so the comment has to be on it
+		if c.EventId != e.Id {
+			a.renderErrorNotif(w, comment.ErrNoComment, http.StatusNotFound)
+			return
+		}
+
+		err = a.commentService.Delete(comment.DeleteParams{
+			Id:          commentId,
+			UserId:      u.Id,
+			IsModerator: canModerateComments(u, e),
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if c.UserId != u.Id {
+			err = a.auditlogService.Create(
+				u.Id,
+				fmt.Sprintf("Removed a comment by %s on <a href=\"/event/%s\">%s</a>", template.HTMLEscapeString(c.UserFullName), e.Id, e.Name),
+			)
+			if err != nil {
+				a.log.Errorf(err.Error())
+			}
+		}
+
+		http.Redirect(w, r, "/event/"+id, http.StatusSeeOther)
+	}
+}
@@ -1,30 +1,47 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
-	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/gorilla/schema"
+	"github.com/mattfan00/jvbe/comment"
 	"github.com/mattfan00/jvbe/event"
 	"github.com/mattfan00/jvbe/group"
+	"github.com/mattfan00/jvbe/ledger"
+	"github.com/mattfan00/jvbe/notification"
+	"github.com/mattfan00/jvbe/team"
+	"github.com/mattfan00/jvbe/venue"
 )
 
+var errLocationRequired = errors.New("choose a venue or enter a location")
+
 func (a *App) renderHome() http.HandlerFunc {
 	type data struct {
 		BaseData
-		CurrEvents []event.Event
-		PastEvents []event.Event
+		CurrEvents   []event.Event
+		PastEvents   []event.Event
+		Tags         []string
+		Tag          string
+		IsFollowing  bool
+		FollowedTags []string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		u, _ := a.sessionUser(r)
+		tag := r.URL.Query().Get("tag")
 
 		currEvents, err := a.eventService.List(event.ListFilter{
 			Upcoming: true,
 			UserId:   u.Id,
+			Tag:      tag,
 		})
 		if err != nil {
 			a.renderErrorPage(w, err, http.StatusInternalServerError)
@@ -36,18 +53,42 @@ func (a *App) renderHome() http.HandlerFunc {
 			OrderByDesc: true,
 			Limit:       10,
 			UserId:      u.Id,
+			Tag:         tag,
 		})
 		if err != nil {
 			a.renderErrorPage(w, err, http.StatusInternalServerError)
 			return
 		}
 
+		tags, err := a.eventService.ListTags()
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		followed, err := a.eventService.ListFollowedTags(u.Id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		isFollowing := false
+		for _, t := range followed {
+			if t == tag {
+				isFollowing = true
+			}
+		}
+
 		a.renderPage(w, "home.html", data{
 			BaseData: BaseData{
 				User: u,
 			},
-			CurrEvents: currEvents.Events,
-			PastEvents: pastEvents.Events,
+			CurrEvents:   currEvents.Events,
+			PastEvents:   pastEvents.Events,
+			Tags:         tags,
+			Tag:          tag,
+			IsFollowing:  isFollowing,
+			FollowedTags: followed,
 		})
 	}
 }
@@ -55,7 +96,13 @@ func (a *App) renderHome() http.HandlerFunc {
 func (a *App) renderNewEvent() http.HandlerFunc {
 	type data struct {
 		BaseData
-		Groups []group.Group
+		Groups               []group.Group
+		Venues               []venue.Venue
+		Templates            []event.EventTemplate
+		Prefill              event.CreateParams
+		MaxDescriptionLength int
+		Timezone             string
+		Timezones            []string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -67,23 +114,59 @@ func (a *App) renderNewEvent() http.HandlerFunc {
 			return
 		}
 
+		v, err := a.venueService.List()
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		t, err := a.eventService.ListTemplates()
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		var prefill event.CreateParams
+		if templateId := r.URL.Query().Get("template"); templateId != "" {
+			et, err := a.eventService.GetTemplate(templateId)
+			if err != nil {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
+				return
+			}
+			prefill = et.CreateParams()
+		}
+		timezone := a.timezone(u.Timezone)
+		if prefill.Timezone != "" {
+			timezone = prefill.Timezone
+		}
+
 		a.renderPage(w, "event/new.html", data{
 			BaseData: BaseData{
 				User: u,
 			},
-			Groups: g,
+			Groups:               g,
+			Venues:               v,
+			Templates:            t,
+			Prefill:              prefill,
+			MaxDescriptionLength: event.MaxDescriptionLength,
+			Timezone:             timezone,
+			Timezones:            commonTimezones,
 		})
 	}
 }
 
 func (a *App) createEvent() http.HandlerFunc {
 	type request struct {
-		Name           string `schema:"name"`
-		GroupId        string `schema:"groupId"`
-		Capacity       int    `schema:"capacity"`
-		Start          string `schema:"start"`
-		TimezoneOffset int    `schema:"timezoneOffset"`
-		Location       string `schema:"location"`
+		Name        string `schema:"name"`
+		GroupId     string `schema:"groupId"`
+		Capacity    int    `schema:"capacity"`
+		Start       string `schema:"start"`
+		Timezone    string `schema:"timezone"`
+		Location    string `schema:"location"`
+		VenueId     string `schema:"venueId"`
+		Description string `schema:"description"`
+		Cost        string `schema:"cost"`
+		Tags        string `schema:"tags"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -95,25 +178,44 @@ func (a *App) createEvent() http.HandlerFunc {
 			return
 		}
 
-		start, err := timeFromForm(req.Start, req.TimezoneOffset)
+		if req.VenueId == "" && strings.TrimSpace(req.Location) == "" {
+			a.renderErrorNotif(w, errLocationRequired, http.StatusBadRequest)
+			return
+		}
+
+		timezone := a.timezone(req.Timezone)
+		start, err := event.ParseLocalTime(req.Start, timezone)
 		if err != nil {
-			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
 			return
 		}
 
-		_, err = a.eventService.Create(event.CreateParams{
-			Name:      req.Name,
-			GroupId:   req.GroupId,
-			Capacity:  req.Capacity,
-			Start:     start,
-			Location:  req.Location,
-			CreatorId: u.Id,
+		cost, err := centsFromForm(req.Cost)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		}
+
+		id, err := a.eventService.Create(event.CreateParams{
+			Name:        req.Name,
+			GroupId:     req.GroupId,
+			Capacity:    req.Capacity,
+			Start:       start,
+			Location:    req.Location,
+			VenueId:     req.VenueId,
+			Description: req.Description,
+			Cost:        cost,
+			Tags:        tagsFromForm(req.Tags),
+			Timezone:    timezone,
+			CreatorId:   u.Id,
 		})
 		if err != nil {
 			a.renderErrorNotif(w, err, http.StatusInternalServerError)
 			return
 		}
 
+		a.notifyTagFollowers(u.Id, id)
+
 		http.Redirect(w, r, "/home", http.StatusSeeOther)
 	}
 }
@@ -121,7 +223,11 @@ func (a *App) createEvent() http.HandlerFunc {
 func (a *App) renderEditEvent() http.HandlerFunc {
 	type data struct {
 		BaseData
-		Event event.Event
+		Event                event.Event
+		Venues               []venue.Venue
+		MaxDescriptionLength int
+		Timezone             string
+		Timezones            []string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -134,22 +240,56 @@ func (a *App) renderEditEvent() http.HandlerFunc {
 			return
 		}
 
+		v, err := a.venueService.List()
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		// a deleted venue is no longer listed, but saving the form should not silently drop it from the event
+		if e.VenueId.Valid && !slices.ContainsFunc(v, func(v venue.Venue) bool { return v.Id == e.VenueId.String }) {
+			v = append(v, venue.Venue{
+				Id:        e.VenueId.String,
+				Name:      e.VenueName.String,
+				IsDeleted: true,
+			})
+		}
+
 		a.renderPage(w, "event/edit.html", data{
 			BaseData: BaseData{
 				User: u,
 			},
-			Event: e,
+			Event:                e,
+			Venues:               v,
+			MaxDescriptionLength: event.MaxDescriptionLength,
+			Timezone:             a.timezone(e.Timezone),
+			Timezones:            commonTimezones,
 		})
 	}
 }
 
 func (a *App) updateEvent() http.HandlerFunc {
 	type request struct {
-		Name           string `schema:"name"`
-		Capacity       int    `schema:"capacity"`
-		Start          string `schema:"start"`
-		TimezoneOffset int    `schema:"timezoneOffset"`
-		Location       string `schema:"location"`
+		Name             string   `schema:"name"`
+		Capacity         int      `schema:"capacity"`
+		Start            string   `schema:"start"`
+		Timezone         string   `schema:"timezone"`
+		Location         string   `schema:"location"`
+		VenueId          string   `schema:"venueId"`
+		Description      string   `schema:"description"`
+		Cost             string   `schema:"cost"`
+		Tags             string   `schema:"tags"`
+		ProtectedUserIds []string `schema:"protectedUserIds"`
+		Confirmed        bool     `schema:"confirmed"`
+	}
+
+	type data struct {
+		BaseData
+		Event     event.Event
+		Request   request
+		Bumped    []event.EventResponse
+		Attending []event.EventResponse
+		Protected map[string]bool
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -168,33 +308,150 @@ func (a *App) updateEvent() http.HandlerFunc {
 			return
 		}
 
-		start, err := timeFromForm(req.Start, req.TimezoneOffset)
+		if req.VenueId == "" && strings.TrimSpace(req.Location) == "" {
+			a.renderErrorNotif(w, errLocationRequired, http.StatusBadRequest)
+			return
+		}
+
+		timezone := a.timezone(req.Timezone)
+		start, err := event.ParseLocalTime(req.Start, timezone)
 		if err != nil {
-			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		}
+
+		cost, err := centsFromForm(req.Cost)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
 			return
 		}
 
-		if err := a.eventService.Update(event.UpdateParams{
-			Id:       id,
-			Name:     req.Name,
-			Capacity: req.Capacity,
-			Start:    start,
-			Location: req.Location,
-		}); err != nil {
+		params := event.UpdateParams{
+			Id:               id,
+			UserId:           u.Id,
+			Name:             req.Name,
+			Capacity:         req.Capacity,
+			Start:            start,
+			Location:         req.Location,
+			VenueId:          req.VenueId,
+			Description:      req.Description,
+			Cost:             cost,
+			Tags:             tagsFromForm(req.Tags),
+			Timezone:         timezone,
+			ProtectedUserIds: req.ProtectedUserIds,
+		}
+
+		// moving attendees to the waitlist needs to be confirmed by the organizer first
+		if !req.Confirmed {
+			preview, err := a.eventService.PreviewUpdate(params)
+			if errors.Is(err, event.ErrCancelled) {
+				a.renderErrorNotif(w, err, http.StatusBadRequest)
+				return
+			} else if err != nil {
+				a.renderErrorNotif(w, err, http.StatusInternalServerError)
+				return
+			}
+
+			bumped := []event.EventResponse{}
+			for _, r := range preview {
+				if r.OnWaitlist {
+					bumped = append(bumped, r)
+				}
+			}
+
+			if len(bumped) > 0 {
+				e, err := a.eventService.Get(id)
+				if err != nil {
+					a.renderErrorNotif(w, err, http.StatusInternalServerError)
+					return
+				}
+
+				responses, err := a.eventService.ListResponses(id)
+				if err != nil {
+					a.renderErrorNotif(w, err, http.StatusInternalServerError)
+					return
+				}
+
+				attending := []event.EventResponse{}
+				for _, r := range responses {
+					if !r.OnWaitlist {
+						attending = append(attending, r)
+					}
+				}
+
+				protected := map[string]bool{}
+				for _, userId := range req.ProtectedUserIds {
+					protected[userId] = true
+				}
+
+				a.renderPage(w, "event/confirm_update.html", data{
+					BaseData: BaseData{
+						User: u,
+					},
+					Event:     e,
+					Request:   req,
+					Bumped:    bumped,
+					Attending: attending,
+					Protected: protected,
+				})
+				return
+			}
+		}
+
+		moved, err := a.eventService.Update(params)
+		if errors.Is(err, event.ErrCancelled) {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		} else if err != nil {
 			a.renderErrorNotif(w, err, http.StatusInternalServerError)
 			return
 		}
 
+		a.notifyWaitlistChanges(u.Id, id, req.Name, moved)
+
 		http.Redirect(w, r, "/event/"+id, http.StatusSeeOther)
 		w.Write(nil)
 	}
 }
 
+// Lets everyone whose waitlist status changed know, except the user who made the change.
+func (a *App) notifyWaitlistChanges(userId string, eventId string, eventName string, moved []event.EventResponse) {
+	waitlisted := []string{}
+	promoted := []string{}
+	for _, r := range moved {
+		if r.UserId == userId {
+			continue
+		}
+		if r.OnWaitlist {
+			waitlisted = append(waitlisted, r.UserId)
+		} else {
+			promoted = append(promoted, r.UserId)
+		}
+	}
+
+	if err := a.notificationService.Create(notification.CreateParams{
+		UserIds: waitlisted,
+		Message: fmt.Sprintf("You have been moved to the waitlist for %s", eventName),
+		Link:    "/event/" + eventId,
+	}); err != nil {
+		a.log.Errorf(err.Error())
+	}
+
+	if err := a.notificationService.Create(notification.CreateParams{
+		UserIds: promoted,
+		Message: fmt.Sprintf("A spot opened up, you are now going to %s", eventName),
+		Link:    "/event/" + eventId,
+	}); err != nil {
+		a.log.Errorf(err.Error())
+	}
+}
+
 func (a *App) deleteEvent() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
 		id := chi.URLParam(r, "id")
 
-		err := a.eventService.Delete(id)
+		err := a.eventService.Delete(id, u.Id)
 		if err != nil {
 			a.renderErrorNotif(w, err, http.StatusInternalServerError)
 			return
@@ -204,11 +461,218 @@ func (a *App) deleteEvent() http.HandlerFunc {
 	}
 }
 
+func (a *App) cancelEvent() http.HandlerFunc {
+	type request struct {
+		Reason string `schema:"reason"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = a.eventService.Cancel(event.CancelParams{
+			Id:     id,
+			UserId: u.Id,
+			Reason: req.Reason,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		e, err := a.eventService.GetDetailed(id, u.Id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		userIds := []string{}
+		for _, r := range e.Responses {
+			if r.UserId != u.Id {
+				userIds = append(userIds, r.UserId)
+			}
+		}
+		err = a.notificationService.Create(notification.CreateParams{
+			UserIds: userIds,
+			Message: fmt.Sprintf("%s has been cancelled: %s", e.Name, e.CancelReason),
+			Link:    "/event/" + id,
+		})
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+
+		err = a.auditlogService.Create(
+			u.Id,
+			fmt.Sprintf("Cancelled <a href=\"/event/%s\">%s</a>", e.Id, e.Name),
+		)
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+
+		http.Redirect(w, r, "/event/"+id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) duplicateEvent() http.HandlerFunc {
+	type request struct {
+		Start           string `schema:"start"`
+		InviteAttendees bool   `schema:"inviteAttendees"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		// the duplicate keeps the timezone of the event
+		e, err := a.eventService.Get(id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		start, err := event.ParseLocalTime(req.Start, a.timezone(e.Timezone))
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		}
+
+		newId, err := a.eventService.Duplicate(event.DuplicateParams{
+			Id:              id,
+			Start:           start,
+			CreatorId:       u.Id,
+			InviteAttendees: req.InviteAttendees,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.notifyTagFollowers(u.Id, newId)
+
+		if req.InviteAttendees {
+			e, err := a.eventService.Get(newId)
+			if err != nil {
+				a.renderErrorNotif(w, err, http.StatusInternalServerError)
+				return
+			}
+
+			invitations, err := a.eventService.ListInvitations(newId)
+			if err != nil {
+				a.renderErrorNotif(w, err, http.StatusInternalServerError)
+				return
+			}
+
+			userIds := []string{}
+			for _, i := range invitations {
+				userIds = append(userIds, i.UserId)
+			}
+			err = a.notificationService.Create(notification.CreateParams{
+				UserIds: userIds,
+				Message: fmt.Sprintf("%s invited you to %s", u.FullName, e.Name),
+				Link:    "/event/" + newId,
+			})
+			if err != nil {
+				a.log.Errorf(err.Error())
+			}
+		}
+
+		http.Redirect(w, r, "/event/"+newId, http.StatusSeeOther)
+	}
+}
+
+func (a *App) createEventTemplate() http.HandlerFunc {
+	type request struct {
+		TemplateName string `schema:"templateName"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		_, err = a.eventService.CreateTemplate(event.CreateTemplateParams{
+			EventId:      id,
+			TemplateName: req.TemplateName,
+			CreatorId:    u.Id,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/event/new", http.StatusSeeOther)
+	}
+}
+
+func (a *App) deleteEventTemplate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		err := a.eventService.DeleteTemplate(id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/event/new", http.StatusSeeOther)
+	}
+}
+
+func (a *App) exportEventCalendar() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		e, err := a.eventService.Get(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err = a.groupService.UserCanAccessError(e.GroupId, u.Id); err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"event-%s.ics\"", id))
+		if err := event.WriteICS(w, e, a.conf.BaseUrl+"/event/"+id); err != nil {
+			a.log.Errorf(err.Error())
+		}
+	}
+}
+
 func (a *App) renderEventDetails() http.HandlerFunc {
 	type data struct {
 		BaseData
 		Event            event.EventDetailed
 		MaxAttendeeCount int
+		Comments         []comment.Comment
+		CanModerate      bool
+		MaxCommentLength int
+		Share            int
+		Payment          *ledger.Entry
+		Teams            []team.Team
+		MaxTeamCount     int
+		Matches          []team.Match
+		Timezone         string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -226,12 +690,53 @@ func (a *App) renderEventDetails() http.HandlerFunc {
 			return
 		}
 
+		c, err := a.commentService.List(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		t, err := a.teamService.ListTeams(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		m, err := a.teamService.ListMatches(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		var payment *ledger.Entry
+		if e.Cost > 0 {
+			entries, err := a.ledgerService.ListEvent(id)
+			if err != nil {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
+				return
+			}
+			for i, entry := range entries {
+				if entry.UserId == u.Id {
+					payment = &entries[i]
+				}
+			}
+		}
+
 		a.renderPage(w, "event/details.html", data{
 			BaseData: BaseData{
 				User: u,
 			},
 			Event:            e,
 			MaxAttendeeCount: event.MaxAttendeeCount,
+			Comments:         c,
+			CanModerate:      canModerateComments(u, e.Event),
+			MaxCommentLength: comment.MaxBodyLength,
+			Share:            ledger.Share(e.Cost, e.TotalAttendeeCount),
+			Payment:          payment,
+			Teams:            t,
+			MaxTeamCount:     team.MaxTeamCount,
+			Matches:          m,
+			Timezone:         a.timezone(e.Timezone),
 		})
 	}
 }
@@ -288,12 +793,17 @@ func (a *App) respondEvent() http.HandlerFunc {
 	}
 }
 
-func timeFromForm(t string, offset int) (time.Time, error) {
-	r, err := time.Parse("2006-01-02T15:04", t)
+// Parses a decimal amount such as "12.50" into cents. An empty amount is 0.
+func centsFromForm(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		return time.Time{}, err
+		return 0, fmt.Errorf("invalid amount %q", s)
 	}
-	r = r.Add(time.Minute * time.Duration(offset))
 
-	return r, nil
+	return int(math.Round(f * 100)), nil
 }
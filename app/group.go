@@ -107,14 +107,16 @@ func (a *App) renderGroupList() http.HandlerFunc {
 func (a *App) inviteGroup() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		u, ok := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
 		if !ok {
 			a.session.Put(r.Context(), "redirect", r.URL.String())
+			// used to auto approve new users invited to trusted groups
+			a.session.Put(r.Context(), "invite", id)
 			http.Redirect(w, r, "/auth/login", http.StatusSeeOther)
 			return
 		}
 
-		id := chi.URLParam(r, "id")
-
 		g, err := a.groupService.AddMemberFromInvite(id, u.Id)
 		if err != nil {
 			a.renderErrorPage(w, err, http.StatusInternalServerError)
@@ -179,9 +181,10 @@ func (a *App) updateGroup() http.HandlerFunc {
 
 func (a *App) deleteGroup() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
 		id := chi.URLParam(r, "id")
 
-		err := a.groupService.Delete(id)
+		err := a.groupService.Delete(id, u.Id)
 		if err != nil {
 			a.renderErrorNotif(w, err, http.StatusInternalServerError)
 			return
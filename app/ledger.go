@@ -0,0 +1,188 @@
+package app
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/group"
+	"github.com/mattfan00/jvbe/ledger"
+)
+
+func (a *App) renderEventLedger() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Event   event.Event
+		Entries []ledger.Entry
+		Share   int
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		e, err := a.eventService.Get(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		entries, err := a.ledgerService.ListEvent(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "event/ledger.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Event:   e,
+			Entries: entries,
+			Share:   ledger.Share(e.Cost, e.TotalAttendeeCount),
+		})
+	}
+}
+
+func (a *App) exportEventLedger() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		entries, err := a.ledgerService.ListEvent(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"event-%s-payments.csv\"", id))
+		if err := ledger.WriteEntriesCSV(w, entries); err != nil {
+			a.log.Errorf(err.Error())
+		}
+	}
+}
+
+func (a *App) markPaid() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+		userId := chi.URLParam(r, "userId")
+
+		e, err := a.eventService.Get(id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = a.ledgerService.MarkPaid(ledger.MarkPaidParams{
+			EventId:  id,
+			UserId:   userId,
+			MarkedBy: u.Id,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		paidUser, err := a.userService.Get(userId)
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+		err = a.auditlogService.Create(
+			u.Id,
+			fmt.Sprintf("Marked %s as paid for <a href=\"/event/%s\">%s</a>", template.HTMLEscapeString(paidUser.FullName), e.Id, e.Name),
+		)
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+
+		http.Redirect(w, r, "/event/"+id+"/ledger", http.StatusSeeOther)
+	}
+}
+
+func (a *App) markUnpaid() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+		userId := chi.URLParam(r, "userId")
+
+		e, err := a.eventService.Get(id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = a.ledgerService.MarkUnpaid(id, userId)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		unpaidUser, err := a.userService.Get(userId)
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+		err = a.auditlogService.Create(
+			u.Id,
+			fmt.Sprintf("Marked %s as unpaid for <a href=\"/event/%s\">%s</a>", template.HTMLEscapeString(unpaidUser.FullName), e.Id, e.Name),
+		)
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+
+		http.Redirect(w, r, "/event/"+id+"/ledger", http.StatusSeeOther)
+	}
+}
+
+func (a *App) renderGroupBalances() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Group    group.Group
+		Balances []ledger.Balance
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		g, err := a.groupService.Get(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		b, err := a.ledgerService.ListGroupBalances(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "group/balances.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Group:    g,
+			Balances: b,
+		})
+	}
+}
+
+func (a *App) exportGroupBalances() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		b, err := a.ledgerService.ListGroupBalances(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"group-%s-balances.csv\"", id))
+		if err := ledger.WriteBalancesCSV(w, b); err != nil {
+			a.log.Errorf(err.Error())
+		}
+	}
+}
@@ -17,19 +17,35 @@ func (a *App) sessionUser(r *http.Request) (user.SessionUser, bool) {
 func (a *App) requireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		u, ok := a.sessionUser(r)
+		if !ok {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
 
-		if ok {
-			if u.Status != user.UserStatusActive {
-				http.Redirect(w, r, "/review/request", http.StatusSeeOther)
-				return
-			} else {
-				next.ServeHTTP(w, r)
+		// recheck the status so that suspensions take effect immediately
+		current, err := a.userService.Get(u.Id)
+		if errors.Is(err, user.ErrNoUser) || current.IsDeleted() || current.IsMerged() {
+			a.session.Destroy(r.Context())
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		} else if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+		if current.Status != u.Status {
+			u.Status = current.Status
+			if err := a.renewSessionUser(r, &u); err != nil {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
 				return
 			}
-		} else {
-			http.Redirect(w, r, "/", http.StatusSeeOther)
+		}
+
+		if u.Status != user.UserStatusActive {
+			http.Redirect(w, r, "/review/request", http.StatusSeeOther)
 			return
 		}
+
+		next.ServeHTTP(w, r)
 	})
 }
 
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/mattfan00/jvbe/notification"
+)
+
+func (a *App) renderNotifications() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Notifications []notification.Notification
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		n, err := a.notificationService.List(u.Id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		// notifications are rendered as unread one last time before being marked read
+		if err := a.notificationService.MarkAllRead(u.Id); err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "notification.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Notifications: n,
+		})
+	}
+}
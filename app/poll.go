@@ -0,0 +1,315 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/group"
+	"github.com/mattfan00/jvbe/notification"
+	"github.com/mattfan00/jvbe/poll"
+	"github.com/mattfan00/jvbe/venue"
+)
+
+func (a *App) renderPollList() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Polls []poll.Poll
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		p, err := a.pollService.List(poll.ListFilter{
+			UserId: u.Id,
+			Open:   true,
+		})
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "poll/list.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Polls: p,
+		})
+	}
+}
+
+func (a *App) renderNewPoll() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Groups               []group.Group
+		Venues               []venue.Venue
+		MaxOptions           int
+		MaxDescriptionLength int
+		Timezone             string
+		Timezones            []string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		g, err := a.groupService.List()
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		v, err := a.venueService.List()
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "poll/new.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Groups:               g,
+			Venues:               v,
+			MaxOptions:           poll.MaxOptions,
+			MaxDescriptionLength: event.MaxDescriptionLength,
+			Timezone:             a.timezone(u.Timezone),
+			Timezones:            commonTimezones,
+		})
+	}
+}
+
+func (a *App) createPoll() http.HandlerFunc {
+	type request struct {
+		Name        string   `schema:"name"`
+		GroupId     string   `schema:"groupId"`
+		Capacity    int      `schema:"capacity"`
+		Starts      []string `schema:"starts"`
+		Timezone    string   `schema:"timezone"`
+		Location    string   `schema:"location"`
+		VenueId     string   `schema:"venueId"`
+		Description string   `schema:"description"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if req.VenueId == "" && strings.TrimSpace(req.Location) == "" {
+			a.renderErrorNotif(w, errLocationRequired, http.StatusBadRequest)
+			return
+		}
+
+		timezone := a.timezone(req.Timezone)
+		starts := []time.Time{}
+		for _, s := range req.Starts {
+			if s == "" { // rows that were added but left empty
+				continue
+			}
+			start, err := event.ParseLocalTime(s, timezone)
+			if err != nil {
+				a.renderErrorNotif(w, err, http.StatusBadRequest)
+				return
+			}
+			starts = append(starts, start)
+		}
+
+		id, err := a.pollService.Create(poll.CreateParams{
+			Name:        req.Name,
+			GroupId:     req.GroupId,
+			Capacity:    req.Capacity,
+			Location:    req.Location,
+			VenueId:     req.VenueId,
+			Description: req.Description,
+			Timezone:    timezone,
+			CreatorId:   u.Id,
+			Starts:      starts,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/poll/"+id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) renderPollDetails() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Poll poll.PollDetailed
+		Best *poll.Option
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		p, err := a.pollService.Get(id, u.Id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err = a.groupService.UserCanAccessError(p.GroupId, u.Id); err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "poll/details.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Poll: p,
+			Best: p.BestOption(),
+		})
+	}
+}
+
+func (a *App) votePoll() http.HandlerFunc {
+	// answers are sent as "answer-<option id>" since each option has its own radio group
+	const answerPrefix = "answer-"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		if err := r.ParseForm(); err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		p, err := a.pollService.Get(id, u.Id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if err = a.groupService.UserCanAccessError(p.GroupId, u.Id); err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		answers := map[string]poll.Answer{}
+		for key := range r.PostForm {
+			if optionId, ok := strings.CutPrefix(key, answerPrefix); ok {
+				answers[optionId] = poll.Answer(r.PostForm.Get(key))
+			}
+		}
+
+		err = a.pollService.Vote(poll.VoteParams{
+			Id:      id,
+			UserId:  u.Id,
+			Answers: answers,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/poll/"+id, http.StatusSeeOther)
+	}
+}
+
+// Creates an event from one of the poll's options and closes the poll.
+func (a *App) convertPoll() http.HandlerFunc {
+	type request struct {
+		OptionId string `schema:"optionId"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		p, err := a.pollService.Get(id, u.Id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if p.IsClosed() {
+			a.renderErrorNotif(w, poll.ErrClosed, http.StatusBadRequest)
+			return
+		}
+
+		var option *poll.Option
+		for i, o := range p.Options {
+			if o.Id == req.OptionId {
+				option = &p.Options[i]
+			}
+		}
+		if option == nil {
+			a.renderErrorNotif(w, poll.ErrNoOption, http.StatusBadRequest)
+			return
+		}
+
+		// closing first makes sure concurrent converts cannot both create an event
+		err = a.pollService.Close(poll.CloseParams{
+			Id:       id,
+			OptionId: option.Id,
+		})
+		if errors.Is(err, poll.ErrClosed) {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		eventId, err := a.eventService.Create(event.CreateParams{
+			Name:        p.Name,
+			GroupId:     p.GroupId.String,
+			Capacity:    p.Capacity,
+			Start:       option.Start,
+			Location:    p.Location,
+			VenueId:     p.VenueId.String,
+			Description: p.Description,
+			Timezone:    p.Timezone,
+			CreatorId:   u.Id,
+		})
+		if err != nil {
+			if err := a.pollService.Reopen(id); err != nil {
+				a.log.Errorf(err.Error())
+			}
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		// the event exists at this point, a missing link only hides it from the poll page
+		err = a.pollService.SetEvent(id, eventId)
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+
+		userIds := []string{}
+		for _, voterId := range p.VoterIds() {
+			if voterId != u.Id {
+				userIds = append(userIds, voterId)
+			}
+		}
+		err = a.notificationService.Create(notification.CreateParams{
+			UserIds: userIds,
+			Message: fmt.Sprintf("%s has been scheduled from a poll you voted in", p.Name),
+			Link:    "/event/" + eventId,
+		})
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+
+		http.Redirect(w, r, "/event/"+eventId, http.StatusSeeOther)
+	}
+}
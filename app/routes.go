@@ -35,9 +35,17 @@ func (a *App) Routes() http.Handler {
 
 		r.Route("/auth", func(r chi.Router) {
 			r.Get("/login", a.renderLogin())
+			r.Get("/login/{provider}", a.redirectToProvider())
 			r.Get("/callback", a.handleLoginCallback())
+			r.Get("/join/{token}", a.handleReferral())
+
+			if a.conf.DevAuth.Enabled {
+				r.Get("/dev", a.renderDevLogin())
+				r.Post("/dev", a.handleDevLogin())
+			}
 
 			r.With(a.requireAuth).Get("/logout", a.handleLogout())
+			r.With(a.requireAuth).Get("/link", a.linkIdentity())
 		})
 
 		r.Group(func(r chi.Router) {
@@ -45,8 +53,18 @@ func (a *App) Routes() http.Handler {
 
 			r.Get("/home", a.renderHome())
 			r.With(a.canDoEverything).Get("/admin", a.renderAdmin())
+			r.With(a.canDoEverything).Get("/admin/merge", a.renderMergeUsers())
+			r.With(a.canDoEverything).Post("/admin/merge", a.mergeUsers())
 			r.With(a.canDoEverything).Get("/auditlog", a.renderAuditlog())
 
+			r.Route("/trash", func(r chi.Router) {
+				r.Use(a.canDoEverything)
+
+				r.Get("/", a.renderTrash())
+				r.Post("/event/{id}/restore", a.restoreEvent())
+				r.Post("/group/{id}/restore", a.restoreGroup())
+			})
+
 			r.Route("/event", func(r chi.Router) {
 				r.Group(func(r chi.Router) {
 					r.Use(a.canModifyEvent)
@@ -56,10 +74,77 @@ func (a *App) Routes() http.Handler {
 					r.Get("/{id}/edit", a.renderEditEvent())
 					r.Post("/{id}/edit", a.updateEvent())
 					r.Delete("/{id}/edit", a.deleteEvent())
+					r.Post("/{id}/cancel", a.cancelEvent())
+					r.Post("/{id}/duplicate", a.duplicateEvent())
+					r.Post("/{id}/template", a.createEventTemplate())
+					r.Delete("/template/{id}", a.deleteEventTemplate())
+					r.Get("/{id}/ledger", a.renderEventLedger())
+					r.Get("/{id}/ledger.csv", a.exportEventLedger())
+					r.Post("/{id}/ledger/{userId}/paid", a.markPaid())
+					r.Delete("/{id}/ledger/{userId}/paid", a.markUnpaid())
+					r.Post("/{id}/teams", a.generateTeams())
+					r.Delete("/{id}/teams", a.deleteTeams())
+					r.Post("/{id}/match", a.createMatch())
+					r.Delete("/{id}/match/{matchId}", a.deleteMatch())
 				})
 
 				r.Get("/{id}", a.renderEventDetails())
+				r.Get("/{id}/calendar.ics", a.exportEventCalendar())
 				r.Post("/respond", a.respondEvent())
+
+				r.Post("/{id}/comment", a.createComment())
+				r.Post("/{id}/comment/{commentId}/edit", a.updateComment())
+				r.Delete("/{id}/comment/{commentId}", a.deleteComment())
+			})
+
+			r.Get("/notification", a.renderNotifications())
+
+			r.Route("/user", func(r chi.Router) {
+				r.Get("/profile/edit", a.renderEditProfile())
+				r.Post("/profile/edit", a.updateProfile())
+				r.Get("/profile/export", a.exportAccount())
+				r.Post("/profile/delete", a.deleteAccount())
+				r.Post("/profile/identity/unlink", a.unlinkIdentity())
+				r.Get("/{id}", a.renderProfile())
+				r.Post("/{id}/vouch", a.confirmVouch())
+				r.Delete("/{id}/vouch", a.deleteVouch())
+
+				r.Group(func(r chi.Router) {
+					r.Use(a.canReviewUser)
+
+					r.Post("/{id}/suspend", a.suspendUser())
+					r.Post("/{id}/reinstate", a.reinstateUser())
+				})
+			})
+
+			r.Route("/tag", func(r chi.Router) {
+				r.Post("/{tag}/follow", a.followTag())
+				r.Delete("/{tag}/follow", a.unfollowTag())
+			})
+
+			r.Route("/poll", func(r chi.Router) {
+				r.Group(func(r chi.Router) {
+					r.Use(a.canModifyEvent)
+
+					r.Get("/new", a.renderNewPoll())
+					r.Post("/new", a.createPoll())
+					r.Post("/{id}/convert", a.convertPoll())
+				})
+
+				r.Get("/list", a.renderPollList())
+				r.Get("/{id}", a.renderPollDetails())
+				r.Post("/{id}/vote", a.votePoll())
+			})
+
+			r.Route("/venue", func(r chi.Router) {
+				r.Use(a.canModifyEvent)
+
+				r.Get("/list", a.renderVenueList())
+				r.Get("/new", a.renderNewVenue())
+				r.Post("/new", a.createVenue())
+				r.Get("/{id}/edit", a.renderEditVenue())
+				r.Post("/{id}/edit", a.updateVenue())
+				r.Delete("/{id}/edit", a.deleteVenue())
 			})
 		})
 
@@ -80,21 +165,29 @@ func (a *App) Routes() http.Handler {
 					r.Delete("/{id}/edit", a.deleteGroup())
 					r.Delete("/{id}/member/{userId}", a.removeGroupMember())
 					r.Post("/{id}/invite", a.refreshInviteLinkGroup())
+					r.Get("/{id}/balances", a.renderGroupBalances())
+					r.Get("/{id}/balances.csv", a.exportGroupBalances())
+					r.Get("/{id}/ratings", a.renderGroupRatings())
+					r.Post("/{id}/ratings", a.updateGroupRatings())
 				})
 
 				r.Get("/{id}", a.renderGroupDetails())
+				r.Get("/{id}/leaderboard", a.renderGroupLeaderboard())
 			})
 		})
 
 		r.Route("/review", func(r chi.Router) {
 			r.Get("/request", a.renderReviewRequest())
 			r.Post("/request", a.updateReview())
+			r.Post("/reapply", a.reapplyReview())
+			r.Post("/vouch", a.requestVouch())
 
 			r.Group(func(r chi.Router) {
 				r.Use(a.canReviewUser)
 
 				r.Get("/list", a.renderReviewList())
 				r.Post("/approve", a.approveReview())
+				r.Post("/reject", a.rejectReview())
 			})
 		})
 
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mattfan00/jvbe/notification"
+)
+
+func (a *App) followTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		tag := chi.URLParam(r, "tag")
+
+		err := a.eventService.FollowTag(u.Id, tag)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		}
+
+		http.Redirect(w, r, "/home?tag="+url.QueryEscape(tag), http.StatusSeeOther)
+	}
+}
+
+func (a *App) unfollowTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		tag := chi.URLParam(r, "tag")
+
+		err := a.eventService.UnfollowTag(u.Id, tag)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/home?tag="+url.QueryEscape(tag), http.StatusSeeOther)
+	}
+}
+
+// Lets users following any of the event's tags know about it, except the creator
+// and users who can't access the event's group.
+func (a *App) notifyTagFollowers(userId string, eventId string) {
+	e, err := a.eventService.Get(eventId)
+	if err != nil {
+		a.log.Errorf(err.Error())
+		return
+	}
+	if len(e.Tags) == 0 {
+		return
+	}
+
+	followers, err := a.eventService.ListTagFollowers(e.Tags)
+	if err != nil {
+		a.log.Errorf(err.Error())
+		return
+	}
+
+	userIds := []string{}
+	for _, followerId := range followers {
+		if followerId == userId {
+			continue
+		}
+		ok, err := a.groupService.UserCanAccess(e.GroupId, followerId)
+		if err != nil {
+			a.log.Errorf(err.Error())
+			return
+		}
+		if ok {
+			userIds = append(userIds, followerId)
+		}
+	}
+
+	err = a.notificationService.Create(notification.CreateParams{
+		UserIds: userIds,
+		Message: fmt.Sprintf("New %s event: %s", strings.Join(e.Tags, ", "), e.Name),
+		Link:    "/event/" + eventId,
+	})
+	if err != nil {
+		a.log.Errorf(err.Error())
+	}
+}
+
+// Splits a comma separated list of tags, the event service does the rest of the validation.
+func tagsFromForm(s string) []string {
+	tags := []string{}
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,232 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mattfan00/jvbe/group"
+	"github.com/mattfan00/jvbe/team"
+)
+
+func (a *App) generateTeams() http.HandlerFunc {
+	type request struct {
+		TeamCount int `schema:"teamCount"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		_, err = a.teamService.Generate(team.GenerateParams{
+			EventId:   id,
+			TeamCount: req.TeamCount,
+			CreatedBy: u.Id,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/event/"+id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) deleteTeams() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		if err := a.teamService.DeleteTeams(id); err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/event/"+id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) renderGroupRatings() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Group         group.Group
+		Ratings       []team.Rating
+		MaxRating     int
+		DefaultRating int
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		g, err := a.groupService.Get(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		ratings, err := a.teamService.ListRatings(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "group/ratings.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Group:         g,
+			Ratings:       ratings,
+			MaxRating:     team.MaxRating,
+			DefaultRating: team.DefaultRating,
+		})
+	}
+}
+
+func (a *App) updateGroupRatings() http.HandlerFunc {
+	// ratings are sent as "rating-<user id>" so every member can be rated in one form
+	const ratingPrefix = "rating-"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		if err := r.ParseForm(); err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		ratings := map[string]int{}
+		for key := range r.PostForm {
+			userId, ok := strings.CutPrefix(key, ratingPrefix)
+			if !ok || r.PostForm.Get(key) == "" { // left unrated
+				continue
+			}
+
+			rating, err := strconv.Atoi(r.PostForm.Get(key))
+			if err != nil {
+				a.renderErrorNotif(w, err, http.StatusBadRequest)
+				return
+			}
+			ratings[userId] = rating
+		}
+
+		err := a.teamService.SetRatings(team.SetRatingsParams{
+			GroupId:   id,
+			Ratings:   ratings,
+			UpdatedBy: u.Id,
+		})
+		if errors.Is(err, team.ErrInvalidRating) || errors.Is(err, team.ErrNotMember) {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/group/"+id+"/ratings", http.StatusSeeOther)
+	}
+}
+
+func (a *App) createMatch() http.HandlerFunc {
+	type request struct {
+		HomeTeamId string `schema:"homeTeamId"`
+		AwayTeamId string `schema:"awayTeamId"`
+		HomeScore  int    `schema:"homeScore"`
+		AwayScore  int    `schema:"awayScore"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		_, err = a.teamService.CreateMatch(team.CreateMatchParams{
+			EventId:    id,
+			HomeTeamId: req.HomeTeamId,
+			AwayTeamId: req.AwayTeamId,
+			HomeScore:  req.HomeScore,
+			AwayScore:  req.AwayScore,
+			CreatedBy:  u.Id,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/event/"+id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) deleteMatch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		matchId := chi.URLParam(r, "matchId")
+
+		if err := a.teamService.DeleteMatch(id, matchId); errors.Is(err, team.ErrNoMatch) {
+			a.renderErrorNotif(w, err, http.StatusNotFound)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/event/"+id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) renderGroupLeaderboard() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Group group.Group
+		Stats []team.Stats
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		if !u.CanModifyGroup() {
+			if err := a.groupService.UserCanAccessError(sql.NullString{
+				String: id,
+				Valid:  true,
+			}, u.Id); err != nil {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		g, err := a.groupService.Get(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		stats, err := a.teamService.ListGroupStats(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "group/leaderboard.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Group: g,
+			Stats: stats,
+		})
+	}
+}
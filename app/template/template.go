@@ -1,13 +1,18 @@
 package template
 
 import (
+	"bytes"
 	"embed"
 	"errors"
 	"html/template"
 	"sync"
 	"time"
 
+	"github.com/mattfan00/jvbe/event"
 	"github.com/mattfan00/jvbe/logger"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
 )
 
 //go:embed *
@@ -34,11 +39,15 @@ func Parse(files ...string) (*template.Template, error) {
 	t := template.New(files[0])
 
 	t.Funcs(template.FuncMap{
-		"jsTime":   jsTime,
 		"l":        l,
 		"add":      add,
 		"unescape": unescape,
+		"markdown": markdown,
+		"cents":    event.FormatCents,
+		"timeIn":   timeIn,
+		"formTime": formTime,
 	})
+	t.Funcs(locationFuncs(time.UTC))
 
 	t, err := t.ParseFS(templatesFs, files...)
 	if err != nil {
@@ -70,8 +79,49 @@ func (m *Manager) Parse(key string, files []string) (*template.Template, error)
 	return t, nil
 }
 
-func jsTime(t time.Time) string {
-	return t.Format("2006-01-02T15:04:05Z")
+// Returns a copy of the template that formats times in loc, the timezone of the user viewing the page.
+// Cached templates are never executed themselves since html/template can't clone them afterwards.
+func InLocation(t *template.Template, loc *time.Location) (*template.Template, error) {
+	c, err := t.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Funcs(locationFuncs(loc)), nil
+}
+
+const displayTimeLayout = "Mon, Jan 02 3:04 PM MST"
+
+func locationFuncs(loc *time.Location) template.FuncMap {
+	return template.FuncMap{
+		"localTime": func(t time.Time) string {
+			return t.In(loc).Format(displayTimeLayout)
+		},
+		// whether the timezone shows times differently than the viewer's timezone at t
+		"differentZone": func(t time.Time, timezone string) bool {
+			other, err := event.LoadLocation(timezone)
+			if err != nil {
+				return false
+			}
+			_, offset := t.In(loc).Zone()
+			_, otherOffset := t.In(other).Zone()
+			return offset != otherOffset
+		},
+	}
+}
+
+// Formats the time in an IANA timezone rather than the viewer's, e.g. the event's timezone.
+func timeIn(t time.Time, timezone string) string {
+	loc, err := event.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(displayTimeLayout)
+}
+
+// Formats the time as the value of a datetime-local input in an IANA timezone.
+func formTime(t time.Time, timezone string) string {
+	return event.FormatLocalTime(t, timezone)
 }
 
 func l(i int) []int {
@@ -90,3 +140,20 @@ func add(x int, y int) int {
 func unescape(s string) template.HTML {
 	return template.HTML(s)
 }
+
+var (
+	markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.Linkify, extension.Strikethrough))
+	markdownPolicy   = bluemonday.UGCPolicy().AddTargetBlankToFullyQualifiedLinks(true)
+)
+
+// Renders user provided markdown to HTML.
+// Unlike unescape, this is safe to use on untrusted input: raw HTML in the markdown is dropped
+// by goldmark and the output is sanitized again before being marked as safe.
+func markdown(s string) template.HTML {
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(s), &buf); err != nil {
+		return template.HTML(template.HTMLEscapeString(s))
+	}
+
+	return template.HTML(markdownPolicy.SanitizeBytes(buf.Bytes()))
+}
@@ -0,0 +1,66 @@
+package template
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdown(t *testing.T) {
+	t.Run("Renders", func(t *testing.T) {
+		out := string(markdown("**bring** a ball\n\n- water\n- sunscreen"))
+		assert.Contains(t, out, "<strong>bring</strong>")
+		assert.Contains(t, out, "<li>water</li>")
+	})
+
+	t.Run("DropsRawHTML", func(t *testing.T) {
+		out := string(markdown("hi <script>alert(1)</script> <img src=x onerror=alert(1)>"))
+		assert.NotContains(t, out, "<script")
+		assert.NotContains(t, out, "onerror")
+	})
+
+	t.Run("SanitizesLinks", func(t *testing.T) {
+		out := string(markdown("[click](javascript:alert(1))"))
+		assert.NotContains(t, out, "javascript:")
+	})
+}
+
+func TestInLocation(t *testing.T) {
+	parsed, err := template.New("t").
+		Funcs(template.FuncMap{"timeIn": timeIn}).
+		Funcs(locationFuncs(time.UTC)).
+		Parse(`{{localTime .}}|{{differentZone . "America/New_York"}}|{{timeIn . "America/Los_Angeles"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(tm time.Time) string {
+		c, err := InLocation(parsed, newYork)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		if err := c.Execute(&b, tm); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	// 23:00 UTC is 6 PM in the winter and 7 PM in the summer in New York
+	assert.Equal(t, "Mon, Jan 15 6:00 PM EST|false|Mon, Jan 15 3:00 PM PST", render(time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "Mon, Jul 15 7:00 PM EDT|false|Mon, Jul 15 4:00 PM PDT", render(time.Date(2024, 7, 15, 23, 0, 0, 0, time.UTC)))
+
+	// the cached template itself keeps formatting in UTC
+	var b strings.Builder
+	err = parsed.Execute(&b, time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, "Mon, Jan 15 11:00 PM UTC|true|Mon, Jan 15 3:00 PM PST", b.String())
+}
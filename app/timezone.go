@@ -0,0 +1,51 @@
+package app
+
+import (
+	"time"
+
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/user"
+)
+
+// Offered in the timezone pickers, any other IANA timezone can still be typed in.
+var commonTimezones = []string{
+	"America/New_York",
+	"America/Chicago",
+	"America/Denver",
+	"America/Phoenix",
+	"America/Los_Angeles",
+	"America/Anchorage",
+	"Pacific/Honolulu",
+	"America/Toronto",
+	"America/Vancouver",
+	"America/Mexico_City",
+	"America/Sao_Paulo",
+	"Europe/London",
+	"Europe/Paris",
+	"Europe/Berlin",
+	"Africa/Johannesburg",
+	"Asia/Dubai",
+	"Asia/Kolkata",
+	"Asia/Singapore",
+	"Asia/Tokyo",
+	"Australia/Sydney",
+	"Pacific/Auckland",
+	"UTC",
+}
+
+// Falls back to the default timezone from the config if tz is empty.
+func (a *App) timezone(tz string) string {
+	if tz == "" {
+		return a.conf.DefaultTimezone()
+	}
+	return tz
+}
+
+// Timezone to show times in for the user.
+func (a *App) location(u user.SessionUser) *time.Location {
+	loc, err := event.LoadLocation(a.timezone(u.Timezone))
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
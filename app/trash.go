@@ -0,0 +1,102 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/group"
+)
+
+func (a *App) renderTrash() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Events          []event.Event
+		Groups          []group.Group
+		GracePeriodDays int
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		e, err := a.eventService.ListDeleted()
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		g, err := a.groupService.ListDeleted()
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "trash.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Events:          e,
+			Groups:          g,
+			GracePeriodDays: a.conf.TrashGracePeriodDays(),
+		})
+	}
+}
+
+func (a *App) restoreEvent() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		err := a.eventService.Restore(id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		e, err := a.eventService.Get(id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = a.auditlogService.Create(
+			u.Id,
+			fmt.Sprintf("Restored deleted event <a href=\"/event/%s\">%s</a>", e.Id, e.Name),
+		)
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+
+		http.Redirect(w, r, "/trash", http.StatusSeeOther)
+	}
+}
+
+func (a *App) restoreGroup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		err := a.groupService.Restore(id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		g, err := a.groupService.Get(id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = a.auditlogService.Create(
+			u.Id,
+			fmt.Sprintf("Restored deleted group <a href=\"/group/%s\">%s</a>", g.Id, g.Name),
+		)
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+
+		http.Redirect(w, r, "/trash", http.StatusSeeOther)
+	}
+}
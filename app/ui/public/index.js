@@ -9,11 +9,3 @@ document.addEventListener("htmx:beforeSwap", (e) => {
         e.detail.isError = false
     }
 })
-
-function formatTime(t) {
-    return dayjs(t).format("ddd, MMM DD h:mm A")
-}
-
-function formFormatTime(t) {
-    return dayjs(t).format("YYYY-MM-DDTHH:mm")
-}
@@ -1,9 +1,20 @@
 package app
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
 	"log"
 	"net/http"
+	"path"
+	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/group"
+	"github.com/mattfan00/jvbe/notification"
 	"github.com/mattfan00/jvbe/user"
 )
 
@@ -11,6 +22,8 @@ func (a *App) renderReviewRequest() http.HandlerFunc {
 	type data struct {
 		BaseData
 		UserReview user.UserReview
+		CanReapply bool
+		MaxVouches int
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -38,6 +51,15 @@ func (a *App) renderReviewRequest() http.HandlerFunc {
 		}
 
 		userReview, err := a.userService.GetReview(su.Id)
+		if errors.Is(err, sql.ErrNoRows) {
+			// has not left a comment yet
+			userReview = user.UserReview{UserId: su.Id, UserStatus: su.Status}
+		} else if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		userReview.Vouches, err = a.userService.ListVouches(su.Id)
 		if err != nil {
 			a.renderErrorPage(w, err, http.StatusInternalServerError)
 			return
@@ -48,10 +70,110 @@ func (a *App) renderReviewRequest() http.HandlerFunc {
 				User: su,
 			},
 			UserReview: userReview,
+			CanReapply: userReview.CanReapply(time.Now()),
+			MaxVouches: user.MaxVouches,
 		})
 	}
 }
 
+func (a *App) requestVouch() http.HandlerFunc {
+	type request struct {
+		VoucherName string `schema:"voucherName"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, ok := a.sessionUser(r)
+		if !ok {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		v, err := a.userService.RequestVouch(user.RequestVouchParams{
+			UserId:      u.Id,
+			VoucherName: req.VoucherName,
+		})
+		if errors.Is(err, user.ErrNoVoucher) ||
+			errors.Is(err, user.ErrAmbiguousVoucher) ||
+			errors.Is(err, user.ErrTooManyVouches) ||
+			errors.Is(err, user.ErrAlreadyVouchedFor) ||
+			errors.Is(err, user.ErrInvalidStatus) {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = a.notificationService.Create(notification.CreateParams{
+			UserIds: []string{v.VoucherId},
+			Message: fmt.Sprintf("%s named you as their referrer, confirm you know them", u.FullName),
+			Link:    "/user/" + u.Id,
+		})
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+
+		http.Redirect(w, r, "/review/request", http.StatusSeeOther)
+	}
+}
+
+func (a *App) confirmVouch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		err := a.userService.ConfirmVouch(id, u.Id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/user/"+id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) deleteVouch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		err := a.userService.DeleteVouch(id, u.Id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/user/"+id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) reapplyReview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, ok := a.sessionUser(r)
+		if !ok {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		err := a.userService.Reapply(u.Id)
+		if errors.Is(err, user.ErrInvalidStatus) || errors.Is(err, user.ErrReapplyCooldown) {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/review/request", http.StatusSeeOther)
+	}
+}
+
 func (a *App) updateReview() http.HandlerFunc {
 	type request struct {
 		Comment string `schema:"comment"`
@@ -82,7 +204,10 @@ func (a *App) updateReview() http.HandlerFunc {
 func (a *App) renderReviewList() http.HandlerFunc {
 	type data struct {
 		BaseData
-		Reviews []user.UserReview
+		Reviews         []user.UserReview
+		RequiredVouches int
+
+		MaxReviewCommentLength int
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -98,19 +223,474 @@ func (a *App) renderReviewList() http.HandlerFunc {
 			BaseData: BaseData{
 				User: u,
 			},
-			Reviews: urs,
+			Reviews:         urs,
+			RequiredVouches: a.conf.Review.RequiredVouches,
+
+			MaxReviewCommentLength: user.MaxReviewCommentLength,
 		})
 	}
 }
 
+type reviewRequest struct {
+	UserId  string `schema:"user_id"`
+	Comment string `schema:"comment"`
+}
+
 func (a *App) approveReview() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		err := a.userService.ApproveReview(r.FormValue("user_id"))
+		u, _ := a.sessionUser(r)
+
+		req, err := schemaDecode[reviewRequest](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = a.userService.ApproveReview(user.ReviewParams{
+			UserId:          req.UserId,
+			ReviewerId:      u.Id,
+			Comment:         req.Comment,
+			RequiredVouches: a.conf.Review.RequiredVouches,
+		})
+		if errors.Is(err, user.ErrInvalidStatus) || errors.Is(err, user.ErrNotEnoughVouches) {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.auditReview(u.Id, req.UserId, "Approved")
+
+		http.Redirect(w, r, "/review/list", http.StatusSeeOther)
+	}
+}
+
+func (a *App) rejectReview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		req, err := schemaDecode[reviewRequest](r)
 		if err != nil {
 			a.renderErrorNotif(w, err, http.StatusInternalServerError)
 			return
 		}
 
+		err = a.userService.RejectReview(user.ReviewParams{
+			UserId:     req.UserId,
+			ReviewerId: u.Id,
+			Comment:    req.Comment,
+		})
+		if errors.Is(err, user.ErrReviewCommentMissing) || errors.Is(err, user.ErrInvalidStatus) {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.auditReview(u.Id, req.UserId, "Rejected")
+
 		http.Redirect(w, r, "/review/list", http.StatusSeeOther)
 	}
 }
+
+func (a *App) suspendUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		req, err := schemaDecode[reviewRequest](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = a.userService.Suspend(user.ReviewParams{
+			UserId:     id,
+			ReviewerId: u.Id,
+			Comment:    req.Comment,
+		})
+		if errors.Is(err, user.ErrReviewCommentMissing) || errors.Is(err, user.ErrInvalidStatus) {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		// free up their spots so the waitlist can move up
+		withdrawn, err := a.eventService.WithdrawUpcomingResponses(id)
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+		for _, wr := range withdrawn {
+			a.notifyWaitlistChanges(id, wr.EventId, wr.EventName, wr.Moved)
+		}
+
+		a.auditReview(u.Id, id, "Suspended")
+
+		http.Redirect(w, r, "/user/"+id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) reinstateUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		req, err := schemaDecode[reviewRequest](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = a.userService.ApproveReview(user.ReviewParams{
+			UserId:     id,
+			ReviewerId: u.Id,
+			Comment:    req.Comment,
+		})
+		if errors.Is(err, user.ErrInvalidStatus) {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.auditReview(u.Id, id, "Reinstated")
+
+		http.Redirect(w, r, "/user/"+id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) auditReview(reviewerId string, userId string, action string) {
+	u, err := a.userService.Get(userId)
+	if err != nil {
+		a.log.Errorf(err.Error())
+		return
+	}
+
+	err = a.auditlogService.Create(
+		reviewerId,
+		fmt.Sprintf("%s <a href=\"/user/%s\">%s</a>", action, u.Id, template.HTMLEscapeString(u.FullName)),
+	)
+	if err != nil {
+		a.log.Errorf(err.Error())
+	}
+}
+
+func (a *App) renderProfile() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Profile     user.User
+		IsMe        bool
+		Groups      []group.Group
+		Upcoming    []event.UserResponse
+		History     []event.UserResponse
+		ShowContact bool
+		Review      user.UserReview // last review decision, only loaded for reviewers
+		ReferralUrl string          // only set when vouching auto approves new users
+		Vouches     []user.Vouch
+		ViewerVouch user.Vouch // vouch the viewer was asked for, if any
+
+		MaxReviewCommentLength int
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		su, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		u, err := a.userService.Get(id)
+		if errors.Is(err, user.ErrNoUser) {
+			a.renderErrorPage(w, err, http.StatusNotFound)
+			return
+		} else if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+		isMe := u.Id == su.Id
+
+		groups, err := a.groupService.ListForUser(u.Id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		// only show the groups, and the events in groups, that the viewer can also see
+		sharesGroup := false
+		visibleGroups := []group.Group{}
+		for _, g := range groups {
+			ok, err := a.groupService.UserCanAccess(sql.NullString{String: g.Id, Valid: true}, su.Id)
+			if err != nil {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
+				return
+			}
+			if ok {
+				sharesGroup = true
+			}
+			if ok || su.CanModifyGroup() {
+				visibleGroups = append(visibleGroups, g)
+			}
+		}
+
+		responses, err := a.eventService.ListUserResponses(u.Id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		upcoming := []event.UserResponse{}
+		history := []event.UserResponse{}
+		for _, resp := range responses {
+			ok, err := a.groupService.UserCanAccess(resp.EventGroupId, su.Id)
+			if err != nil {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				continue
+			}
+
+			if !resp.IsPast {
+				if !resp.IsCancelled() {
+					// soonest first
+					upcoming = append([]event.UserResponse{resp}, upcoming...)
+				}
+			} else if resp.Attended() {
+				history = append(history, resp)
+			}
+		}
+
+		showContact := u.ContactInfo != "" && (isMe ||
+			u.ContactVisibility == user.ContactVisibilityEveryone ||
+			(u.ContactVisibility == user.ContactVisibilityGroupMembers && sharesGroup))
+
+		review := user.UserReview{}
+		if su.CanReviewUser() {
+			review, err = a.userService.GetReview(u.Id)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		vouches, err := a.userService.ListVouches(u.Id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+		viewerVouch := user.Vouch{}
+		for _, v := range vouches {
+			if v.VoucherId == su.Id {
+				viewerVouch = v
+			}
+		}
+
+		referralUrl := ""
+		if isMe && a.conf.AutoApprove.Vouch {
+			token, err := a.userService.ReferralToken(u.Id)
+			if err != nil {
+				a.renderErrorPage(w, err, http.StatusInternalServerError)
+				return
+			}
+			referralUrl = a.conf.BaseUrl + "/auth/join/" + token
+		}
+
+		a.renderPage(w, "user/profile.html", data{
+			BaseData: BaseData{
+				User: su,
+			},
+			Profile:     u,
+			IsMe:        isMe,
+			Groups:      visibleGroups,
+			Upcoming:    upcoming,
+			History:     history,
+			ShowContact: showContact,
+			Review:      review,
+			ReferralUrl: referralUrl,
+			Vouches:     vouches,
+			ViewerVouch: viewerVouch,
+
+			MaxReviewCommentLength: user.MaxReviewCommentLength,
+		})
+	}
+}
+
+func (a *App) renderEditProfile() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Profile              user.User
+		MaxFullNameLength    int
+		MaxBioLength         int
+		MaxContactInfoLength int
+		Timezones            []string
+		DefaultTimezone      string
+		Identities           []user.Identity
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		su, _ := a.sessionUser(r)
+
+		u, err := a.userService.Get(su.Id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		identities, err := a.userService.ListIdentities(su.Id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "user/edit.html", data{
+			BaseData: BaseData{
+				User: su,
+			},
+			Profile:              u,
+			MaxFullNameLength:    user.MaxFullNameLength,
+			MaxBioLength:         user.MaxBioLength,
+			MaxContactInfoLength: user.MaxContactInfoLength,
+			Timezones:            commonTimezones,
+			DefaultTimezone:      a.conf.DefaultTimezone(),
+			Identities:           identities,
+		})
+	}
+}
+
+func (a *App) updateProfile() http.HandlerFunc {
+	type request struct {
+		FullName          string                 `schema:"fullName"`
+		Bio               string                 `schema:"bio"`
+		ContactInfo       string                 `schema:"contactInfo"`
+		ContactVisibility user.ContactVisibility `schema:"contactVisibility"`
+		Timezone          string                 `schema:"timezone"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		su, _ := a.sessionUser(r)
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		u, err := a.userService.UpdateProfile(user.UpdateProfileParams{
+			Id:                su.Id,
+			FullName:          req.FullName,
+			Bio:               req.Bio,
+			ContactInfo:       req.ContactInfo,
+			ContactVisibility: req.ContactVisibility,
+			Timezone:          req.Timezone,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		}
+
+		// the name and timezone are used from the session
+		su.FullName = u.FullName
+		su.Timezone = u.Timezone
+		if err := a.renewSessionUser(r, &su); err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/user/"+su.Id, http.StatusSeeOther)
+	}
+}
+
+func (a *App) unlinkIdentity() http.HandlerFunc {
+	type request struct {
+		Issuer  string `schema:"issuer"`
+		Subject string `schema:"subject"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		su, _ := a.sessionUser(r)
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = a.userService.UnlinkIdentity(su.Id, req.Issuer, req.Subject)
+		if errors.Is(err, user.ErrLastIdentity) {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/user/profile/edit", http.StatusSeeOther)
+	}
+}
+
+func (a *App) renderMergeUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		a.renderPage(w, "user/merge.html", BaseData{
+			User: u,
+		})
+	}
+}
+
+func (a *App) mergeUsers() http.HandlerFunc {
+	type request struct {
+		FromId string `schema:"fromId"`
+		ToId   string `schema:"toId"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		// accept profile links as well as ids
+		res, err := a.userService.Merge(user.MergeParams{
+			FromId: path.Base(strings.TrimSpace(req.FromId)),
+			ToId:   path.Base(strings.TrimSpace(req.ToId)),
+		})
+		if errors.Is(err, user.ErrNoUser) || errors.Is(err, user.ErrInvalidStatus) {
+			a.renderErrorNotif(w, err, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		// only one response is kept for events both users responded to, which may free up spots
+		for _, eventId := range res.ConflictEventIds {
+			e, err := a.eventService.Get(eventId)
+			if err != nil {
+				a.log.Errorf(err.Error())
+				continue
+			}
+			a.notifyWaitlistChanges(res.To.Id, e.Id, e.Name, res.Moved[eventId])
+		}
+
+		err = a.auditlogService.Create(
+			u.Id,
+			fmt.Sprintf(
+				"Merged %s into <a href=\"/user/%s\">%s</a>",
+				template.HTMLEscapeString(res.From.FullName),
+				res.To.Id,
+				template.HTMLEscapeString(res.To.FullName),
+			),
+		)
+		if err != nil {
+			a.log.Errorf(err.Error())
+		}
+
+		http.Redirect(w, r, "/user/"+res.To.Id, http.StatusSeeOther)
+	}
+}
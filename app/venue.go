@@ -0,0 +1,165 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mattfan00/jvbe/venue"
+)
+
+func (a *App) renderVenueList() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Venues []venue.Venue
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		v, err := a.venueService.List()
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "venue/list.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Venues: v,
+		})
+	}
+}
+
+func (a *App) renderNewVenue() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Timezones []string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		a.renderPage(w, "venue/new.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Timezones: commonTimezones,
+		})
+	}
+}
+
+func (a *App) createVenue() http.HandlerFunc {
+	type request struct {
+		Name            string `schema:"name"`
+		Address         string `schema:"address"`
+		MapUrl          string `schema:"mapUrl"`
+		Notes           string `schema:"notes"`
+		DefaultCapacity int    `schema:"defaultCapacity"`
+		Timezone        string `schema:"timezone"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		_, err = a.venueService.Create(venue.CreateParams{
+			Name:            req.Name,
+			Address:         req.Address,
+			MapUrl:          req.MapUrl,
+			Notes:           req.Notes,
+			DefaultCapacity: req.DefaultCapacity,
+			Timezone:        req.Timezone,
+			CreatorId:       u.Id,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/venue/list", http.StatusSeeOther)
+	}
+}
+
+func (a *App) renderEditVenue() http.HandlerFunc {
+	type data struct {
+		BaseData
+		Venue     venue.Venue
+		Timezones []string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, _ := a.sessionUser(r)
+		id := chi.URLParam(r, "id")
+
+		v, err := a.venueService.Get(id)
+		if err != nil {
+			a.renderErrorPage(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		a.renderPage(w, "venue/edit.html", data{
+			BaseData: BaseData{
+				User: u,
+			},
+			Venue:     v,
+			Timezones: commonTimezones,
+		})
+	}
+}
+
+func (a *App) updateVenue() http.HandlerFunc {
+	type request struct {
+		Name            string `schema:"name"`
+		Address         string `schema:"address"`
+		MapUrl          string `schema:"mapUrl"`
+		Notes           string `schema:"notes"`
+		DefaultCapacity int    `schema:"defaultCapacity"`
+		Timezone        string `schema:"timezone"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		req, err := schemaDecode[request](r)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		err = a.venueService.Update(venue.UpdateParams{
+			Id:              id,
+			Name:            req.Name,
+			Address:         req.Address,
+			MapUrl:          req.MapUrl,
+			Notes:           req.Notes,
+			DefaultCapacity: req.DefaultCapacity,
+			Timezone:        req.Timezone,
+		})
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/venue/list", http.StatusSeeOther)
+	}
+}
+
+func (a *App) deleteVenue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		err := a.venueService.Delete(id)
+		if err != nil {
+			a.renderErrorNotif(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/venue/list", http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,264 @@
+package auditlog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattfan00/jvbe/db"
+)
+
+// A single line of an archive file.
+// Hashes are kept so that the archived entries can still be checked against the chain.
+type archivedEntry struct {
+	RowId       int64     `json:"row_id"`
+	UserId      string    `json:"user_id"`
+	RecordedAt  time.Time `json:"recorded_at"`
+	Description string    `json:"description"`
+	PrevHash    string    `json:"prev_hash"`
+	Hash        string    `json:"hash"`
+}
+
+// Writes all entries recorded before the given time to a gzipped JSONL file in dir and deletes them.
+// The entries are only deleted once the archive file has been written successfully.
+// Entries recorded out of order are archived along with the older entries around them,
+// so that the remaining entries always continue the chain from the last archived one.
+//
+// Returns the path of the archive file and the number of archived entries.
+// If there are no entries to archive, no file is written.
+func (s *service) Archive(before time.Time, dir string) (string, int, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return "", 0, err
+	}
+	defer tx.Rollback()
+
+	entries, err := listBefore(tx, before)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(entries) == 0 {
+		return "", 0, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("audit_log_%s.jsonl.gz", db.Now().Format("20060102T150405Z")))
+	if err := writeArchive(path, entries); err != nil {
+		return "", 0, err
+	}
+
+	if err := deleteBefore(tx, before); err != nil {
+		return "", 0, err
+	}
+	// entries without a hash were recorded before the chain and leave the anchor as is
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Hash != "" {
+			if err := setAnchorHash(tx, entries[i].Hash); err != nil {
+				return "", 0, err
+			}
+			break
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", 0, err
+	}
+
+	s.log.Printf("archived %d audit log entries to %s", len(entries), path)
+	return path, len(entries), nil
+}
+
+var tableNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Loads an archive file into the given scratch table so that it can be queried for investigation.
+// The table is created if it does not exist. Restoring into audit_log itself is not allowed.
+//
+// Returns the number of restored entries.
+func (s *service) Restore(src string, table string) (int, error) {
+	if !tableNameRegexp.MatchString(table) {
+		return 0, fmt.Errorf("invalid table name: %s", table)
+	}
+	if table == "audit_log" {
+		return 0, errors.New("cannot restore into audit_log, use a scratch table")
+	}
+
+	entries, err := readArchive(src)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	err = restore(tx, table, entries)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	s.log.Printf("restored %d audit log entries from %s into %s", len(entries), src, table)
+	return len(entries), nil
+}
+
+// Archives entries older than retentionDays once immediately and then every interval.
+// Blocks until ctx is done, so it is meant to be run in its own goroutine.
+func (s *service) RunRetention(ctx context.Context, retentionDays int, dir string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		before := db.Now().AddDate(0, 0, -retentionDays)
+		if _, _, err := s.Archive(before, dir); err != nil {
+			s.log.Errorf("archiving audit log: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func writeArchive(path string, entries []AuditLog) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	enc := json.NewEncoder(gz)
+	for _, a := range entries {
+		err := enc.Encode(archivedEntry{
+			RowId:       a.RowId,
+			UserId:      a.UserId,
+			RecordedAt:  a.RecordedAt,
+			Description: a.Description,
+			PrevHash:    a.PrevHash,
+			Hash:        a.Hash,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func readArchive(src string) ([]archivedEntry, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return []archivedEntry{}, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return []archivedEntry{}, err
+	}
+	defer gz.Close()
+
+	entries := []archivedEntry{}
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e archivedEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return []archivedEntry{}, err
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return []archivedEntry{}, err
+	}
+
+	return entries, nil
+}
+
+// The chain follows rowid, so everything up to the last entry recorded before the given time is archived.
+const beforeRowId = `(SELECT MAX(rowid) FROM audit_log WHERE datetime(recorded_at) < datetime(?))`
+
+func listBefore(tx *sqlx.Tx, before time.Time) ([]AuditLog, error) {
+	stmt := `
+        SELECT rowid, user_id, recorded_at, description, prev_hash, hash
+        FROM audit_log
+        WHERE rowid <= ` + beforeRowId + `
+        ORDER BY rowid ASC
+    `
+	args := []any{before}
+
+	var al []AuditLog
+	err := tx.Select(&al, stmt, args...)
+	return al, err
+}
+
+func deleteBefore(tx *sqlx.Tx, before time.Time) error {
+	stmt := `
+        DELETE FROM audit_log
+        WHERE rowid <= ` + beforeRowId + `
+    `
+	args := []any{before}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
+
+func restore(tx *sqlx.Tx, table string, entries []archivedEntry) error {
+	// table name is validated by the caller since it cannot be passed as a parameter
+	stmt := `
+        CREATE TABLE IF NOT EXISTS ` + table + ` (
+            row_id INTEGER NOT NULL,
+            user_id TEXT NOT NULL,
+            recorded_at DATETIME NOT NULL,
+            description TEXT NOT NULL,
+            prev_hash TEXT NOT NULL,
+            hash TEXT NOT NULL
+        )
+    `
+	if _, err := tx.Exec(stmt); err != nil {
+		return err
+	}
+
+	stmt = `
+        INSERT INTO ` + table + ` (row_id, user_id, recorded_at, description, prev_hash, hash)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `
+	for _, e := range entries {
+		args := []any{
+			e.RowId,
+			e.UserId,
+			e.RecordedAt,
+			e.Description,
+			e.PrevHash,
+			e.Hash,
+		}
+		if _, err := tx.Exec(stmt, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,16 +1,48 @@
 package auditlog
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
 
 type Service interface {
 	Create(string, string) error
 	List(ListFilter) ([]AuditLog, int, error)
+	Verify() (*BrokenLink, error)
+	Archive(time.Time, string) (string, int, error)
+	Restore(string, string) (int, error)
 }
 
 type AuditLog struct {
+	RowId        int64     `db:"rowid"`
 	UserId       string    `db:"user_id"`
 	UserFullName string    `db:"user_full_name"`
 	RecordedAt   time.Time `db:"recorded_at"`
 	Description  string    `db:"description"`
+	PrevHash     string    `db:"prev_hash"`
+	Hash         string    `db:"hash"`
 	Count        int       `db:"count"`
 }
+
+// Hashes the content of the entry together with the hash of the entry before it,
+// so that changing or removing any entry breaks every link after it.
+func (a AuditLog) computeHash() string {
+	content := strings.Join([]string{
+		a.PrevHash,
+		a.UserId,
+		a.RecordedAt.UTC().Format(time.RFC3339Nano),
+		a.Description,
+	}, "\n")
+
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// The first entry in the audit log that does not match the hash chain.
+type BrokenLink struct {
+	RowId      int64
+	RecordedAt time.Time
+	Reason     string
+}
@@ -1,20 +1,31 @@
 package auditlog
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/logger"
 )
 
 type service struct {
-	db *db.DB
+	db  *db.DB
+	log logger.Logger
 }
 
 func NewService(db *db.DB) *service {
 	return &service{
-		db: db,
+		db:  db,
+		log: logger.NewNoopLogger(),
 	}
 }
 
+func (s *service) SetLogger(l logger.Logger) {
+	s.log = l
+}
+
 func (s *service) Create(userId string, description string) error {
 	tx, err := s.db.Beginx()
 	if err != nil {
@@ -31,6 +42,7 @@ func (s *service) Create(userId string, description string) error {
 }
 
 type ListFilter struct {
+	UserId string // only entries recorded by the user
 	Limit  int
 	Offset int
 }
@@ -48,37 +60,162 @@ func (s *service) List(f ListFilter) ([]AuditLog, int, error) {
 
 var al = []AuditLog{}
 
+// Walks the audit log from oldest to newest and returns the first entry that breaks the hash chain.
+// Returns nil if the chain is intact.
+func (s *service) Verify() (*BrokenLink, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return verify(tx)
+}
+
 func create(tx *sqlx.Tx, userId string, description string) error {
+	prevHash, err := getLastHash(tx)
+	if err != nil {
+		return err
+	}
+
+	a := AuditLog{
+		UserId:      userId,
+		RecordedAt:  db.Now(),
+		Description: description,
+		PrevHash:    prevHash,
+	}
+	a.Hash = a.computeHash()
+
 	stmt := `
-        INSERT INTO audit_log (user_id, recorded_at, description)
-        VALUES (?, ?, ?)
+        INSERT INTO audit_log (user_id, recorded_at, description, prev_hash, hash)
+        VALUES (?, ?, ?, ?, ?)
     `
 	args := []any{
-		userId,
-		db.Now(),
-		description,
+		a.UserId,
+		a.RecordedAt,
+		a.Description,
+		a.PrevHash,
+		a.Hash,
+	}
+
+	_, err = tx.Exec(stmt, args...)
+	return err
+}
+
+func getLastHash(tx *sqlx.Tx) (string, error) {
+	stmt := `
+        SELECT hash FROM audit_log
+        ORDER BY rowid DESC
+        LIMIT 1
+    `
+
+	var hash string
+	err := tx.Get(&hash, stmt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return hash, err
+}
+
+func verify(tx *sqlx.Tx) (*BrokenLink, error) {
+	anchor, err := getAnchor(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `
+        SELECT rowid, user_id, recorded_at, description, prev_hash, hash
+        FROM audit_log
+        ORDER BY rowid ASC
+    `
+
+	rows, err := tx.Queryx(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// the chain continues from the last archived entry, or starts empty if nothing was archived yet
+	prevHash := anchor.Hash
+	for rows.Next() {
+		var a AuditLog
+		if err := rows.StructScan(&a); err != nil {
+			return nil, err
+		}
+
+		broken := &BrokenLink{
+			RowId:      a.RowId,
+			RecordedAt: a.RecordedAt,
+		}
+		if a.Hash == "" {
+			// entries recorded before the hash chain was introduced have no hash
+			if a.RowId <= anchor.LegacyRowId {
+				continue
+			}
+			broken.Reason = "missing hash"
+			return broken, nil
+		}
+		if a.PrevHash != prevHash {
+			broken.Reason = fmt.Sprintf("previous hash %s does not match %s", a.PrevHash, prevHash)
+			return broken, nil
+		}
+		if computed := a.computeHash(); computed != a.Hash {
+			broken.Reason = fmt.Sprintf("hash %s does not match content hash %s", a.Hash, computed)
+			return broken, nil
+		}
+
+		prevHash = a.Hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	_, err := tx.Exec(stmt, args...)
+	return nil, nil
+}
+
+type anchor struct {
+	LegacyRowId int64  `db:"legacy_row_id"`
+	Hash        string `db:"hash"`
+}
+
+func getAnchor(tx *sqlx.Tx) (anchor, error) {
+	var a anchor
+	err := tx.Get(&a, `SELECT legacy_row_id, hash FROM audit_log_anchor WHERE id = 1`)
+	return a, err
+}
+
+// Remembers the hash of the last archived entry so the remaining entries can still be checked against it.
+func setAnchorHash(tx *sqlx.Tx, hash string) error {
+	_, err := tx.Exec(`UPDATE audit_log_anchor SET hash = ? WHERE id = 1`, hash)
 	return err
 }
 
 func list(tx *sqlx.Tx, f ListFilter) ([]AuditLog, int, error) {
+	where := "1 = 1"
+	args := []any{}
+	if f.UserId != "" {
+		where = "u.id = ?"
+		args = append(args, f.UserId)
+	}
+
+	// entries stay with the user they were recorded by, merged users are shown as the user they were merged into
 	stmt := `
         SELECT 
-            user_id
+            u.id AS user_id
             ,u.full_name AS user_full_name
             ,recorded_at
             ,description 
             ,COUNT(*) OVER () AS count
         FROM audit_log al
-        INNER JOIN user u ON al.user_id = u.id
+        INNER JOIN user ru ON al.user_id = ru.id
+        INNER JOIN user u ON COALESCE(ru.merged_into_id, ru.id) = u.id
+        WHERE ` + where + `
         ORDER BY recorded_at DESC
         ` + db.FormatLimitOffset(f.Limit, f.Offset)
 
 	var al []AuditLog
 	var count = 0
-	err := tx.Select(&al, stmt)
+	err := tx.Select(&al, stmt, args...)
 	if err != nil {
 		return []AuditLog{}, count, err
 	}
@@ -1,7 +1,9 @@
 package auditlog_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/mattfan00/jvbe/auditlog"
 	"github.com/mattfan00/jvbe/db"
@@ -46,7 +48,7 @@ func TestList(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-    assert.Equal(t, 0, count)
+	assert.Equal(t, 0, count)
 
 	u1, err := userService.Create(user.CreateParams{FullName: "one"})
 	if err != nil {
@@ -74,4 +76,304 @@ func TestList(t *testing.T) {
 	assert.Equal(t, 2, count)
 	assert.Equal(t, u2.Id, al[0].UserId)
 	assert.Equal(t, u1.Id, al[1].UserId)
+
+	al, count, err = auditlogService.List(auditlog.ListFilter{UserId: u1.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "test1", al[0].Description)
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("Intact", func(t *testing.T) {
+		db := db.TestingConnect(t)
+		defer db.Close()
+		auditlogService := auditlog.NewService(db)
+		userService := user.NewService(db)
+
+		u, err := userService.Create(user.CreateParams{FullName: "name"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, d := range []string{"test1", "test2", "test3"} {
+			err = auditlogService.Create(u.Id, d)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		broken, err := auditlogService.Verify()
+		assert.NoError(t, err)
+		assert.Nil(t, broken)
+	})
+
+	t.Run("TamperedDescription", func(t *testing.T) {
+		db := db.TestingConnect(t)
+		defer db.Close()
+		auditlogService := auditlog.NewService(db)
+		userService := user.NewService(db)
+
+		u, err := userService.Create(user.CreateParams{FullName: "name"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, d := range []string{"test1", "test2", "test3"} {
+			err = auditlogService.Create(u.Id, d)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		_, err = db.Exec("UPDATE audit_log SET description = 'changed' WHERE description = 'test2'")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		broken, err := auditlogService.Verify()
+		assert.NoError(t, err)
+		if assert.NotNil(t, broken) {
+			assert.Equal(t, int64(2), broken.RowId)
+		}
+	})
+
+	t.Run("DeletedEntry", func(t *testing.T) {
+		db := db.TestingConnect(t)
+		defer db.Close()
+		auditlogService := auditlog.NewService(db)
+		userService := user.NewService(db)
+
+		u, err := userService.Create(user.CreateParams{FullName: "name"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, d := range []string{"test1", "test2", "test3"} {
+			err = auditlogService.Create(u.Id, d)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		_, err = db.Exec("DELETE FROM audit_log WHERE description = 'test2'")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		broken, err := auditlogService.Verify()
+		assert.NoError(t, err)
+		if assert.NotNil(t, broken) {
+			assert.Equal(t, int64(3), broken.RowId)
+		}
+	})
+}
+
+func TestVerifyAnchor(t *testing.T) {
+	setup := func(t *testing.T) (*db.DB, auditlog.Service) {
+		db := db.TestingConnect(t)
+		auditlogService := auditlog.NewService(db)
+		userService := user.NewService(db)
+
+		u, err := userService.Create(user.CreateParams{FullName: "name"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, d := range []string{"test1", "test2", "test3"} {
+			err = auditlogService.Create(u.Id, d)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return db, auditlogService
+	}
+
+	t.Run("ClearedHashes", func(t *testing.T) {
+		db, auditlogService := setup(t)
+		defer db.Close()
+
+		// a rewritten prefix without hashes could pass for entries from before the hash chain
+		_, err := db.Exec("UPDATE audit_log SET description = 'changed', hash = '', prev_hash = '' WHERE description <> 'test3'")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		broken, err := auditlogService.Verify()
+		assert.NoError(t, err)
+		if assert.NotNil(t, broken) {
+			assert.Equal(t, int64(1), broken.RowId)
+			assert.Equal(t, "missing hash", broken.Reason)
+		}
+	})
+
+	t.Run("LegacyEntries", func(t *testing.T) {
+		db, auditlogService := setup(t)
+		defer db.Close()
+
+		_, err := db.Exec("UPDATE audit_log_anchor SET legacy_row_id = 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.Exec("UPDATE audit_log SET hash = '' WHERE rowid = 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the chain starts at the first hashed entry, the legacy entry's hash was empty
+		_, err = db.Exec("DELETE FROM audit_log WHERE rowid = 2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		broken, err := auditlogService.Verify()
+		assert.NoError(t, err)
+		if assert.NotNil(t, broken) {
+			assert.Equal(t, int64(3), broken.RowId)
+		}
+	})
+
+	t.Run("DeletedAfterArchive", func(t *testing.T) {
+		db, auditlogService := setup(t)
+		defer db.Close()
+
+		_, err := db.Exec("UPDATE audit_log SET recorded_at = datetime('now', '-100 days') WHERE description = 'test1'")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = auditlogService.Archive(time.Now().AddDate(0, 0, -30), t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		broken, err := auditlogService.Verify()
+		assert.NoError(t, err)
+		assert.Nil(t, broken)
+
+		_, err = db.Exec("DELETE FROM audit_log WHERE description = 'test2'")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		broken, err = auditlogService.Verify()
+		assert.NoError(t, err)
+		if assert.NotNil(t, broken) {
+			assert.Equal(t, int64(3), broken.RowId)
+		}
+	})
+}
+
+func TestArchive(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	auditlogService := auditlog.NewService(db)
+	userService := user.NewService(db)
+
+	u, err := userService.Create(user.CreateParams{FullName: "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range []string{"old1", "old2", "new"} {
+		err = auditlogService.Create(u.Id, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// backdate the first two entries so they fall outside of the retention period
+	_, err = db.Exec("UPDATE audit_log SET recorded_at = datetime('now', '-100 days') WHERE description <> 'new'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	path, count, err := auditlogService.Archive(time.Now().AddDate(0, 0, -30), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, count)
+
+	al, _, err := auditlogService.List(auditlog.ListFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(al))
+	assert.Equal(t, "new", al[0].Description)
+
+	broken, err := auditlogService.Verify()
+	assert.NoError(t, err)
+	assert.Nil(t, broken)
+
+	restored, err := auditlogService.Restore(path, "scratch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, restored)
+
+	var descriptions []string
+	err = db.Select(&descriptions, "SELECT description FROM scratch ORDER BY row_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"old1", "old2"}, descriptions)
+
+	_, err = auditlogService.Restore(path, "audit_log")
+	assert.Error(t, err)
+}
+
+func TestArchiveOutOfOrder(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	auditlogService := auditlog.NewService(db)
+	userService := user.NewService(db)
+
+	u, err := userService.Create(user.CreateParams{FullName: "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range []string{"recent", "old", "new"} {
+		err = auditlogService.Create(u.Id, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// an entry recorded with an older time than the one before it, e.g. after the clock was adjusted
+	_, err = db.Exec("UPDATE audit_log SET recorded_at = datetime('now', '-100 days') WHERE description = 'old'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, count, err := auditlogService.Archive(time.Now().AddDate(0, 0, -30), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, count)
+
+	broken, err := auditlogService.Verify()
+	assert.NoError(t, err)
+	assert.Nil(t, broken)
+}
+
+func TestRunRetention(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	auditlogService := auditlog.NewService(db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		auditlogService.RunRetention(ctx, 30, t.TempDir(), time.Hour)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("retention did not stop")
+	}
 }
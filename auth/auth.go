@@ -1,10 +1,22 @@
 package auth
 
 import (
+	"errors"
+
 	"github.com/mattfan00/jvbe/user"
 )
 
+var ErrNoProvider = errors.New("unknown login provider")
+
+type Provider struct {
+	Id   string
+	Name string
+}
+
 type Service interface {
-	AuthCodeUrl(string) string
-	GetExternalUser(code string) (user.ExternalUser, error)
+	Providers() []Provider
+	AuthCodeUrl(providerId string, state string, forceLogin bool) (string, error)
+	// Also returns the raw ID token, which is passed back to the provider on logout.
+	GetExternalUser(providerId string, code string) (user.ExternalUser, string, error)
+	LogoutUrl(providerId string, idToken string, redirectUrl string) (string, error)
 }
@@ -3,6 +3,9 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 
 	"github.com/mattfan00/jvbe/config"
 	"github.com/mattfan00/jvbe/logger"
@@ -13,33 +16,70 @@ import (
 	"golang.org/x/oauth2"
 )
 
-type service struct {
+type provider struct {
+	conf         config.OidcProvider
 	oidcProvider *oidc.Provider
 	oauthConf    *oauth2.Config
-	log          logger.Logger
+	// discovered end_session_endpoint, empty if the provider does not support RP-initiated logout
+	endSessionUrl string
+}
+
+type service struct {
+	providers []provider
+	log       logger.Logger
 }
 
 func NewService(conf *config.Config) (*service, error) {
-	provider, err := oidc.NewProvider(
-		context.Background(),
-		"https://"+conf.Oauth.Domain,
-	)
-	if err != nil {
-		return &service{}, err
+	confs := conf.OidcProviders()
+	if len(confs) == 0 {
+		return &service{}, errors.New("no oauth providers configured")
 	}
 
-	oauthConf := &oauth2.Config{
-		ClientID:     conf.Oauth.ClientId,
-		ClientSecret: conf.Oauth.ClientSecret,
-		RedirectURL:  conf.OauthCallbackUrl(),
-		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
-		Endpoint:     provider.Endpoint(),
+	providers := []provider{}
+	for _, c := range confs {
+		p, err := newProvider(c, conf.OauthCallbackUrl())
+		if err != nil {
+			return &service{}, fmt.Errorf("provider %s: %w", c.Id, err)
+		}
+		providers = append(providers, p)
 	}
 
 	return &service{
-		oidcProvider: provider,
-		oauthConf:    oauthConf,
-		log:          logger.NewNoopLogger(),
+		providers: providers,
+		log:       logger.NewNoopLogger(),
+	}, nil
+}
+
+func newProvider(c config.OidcProvider, callbackUrl string) (provider, error) {
+	oidcProvider, err := oidc.NewProvider(context.Background(), c.IssuerUrl)
+	if err != nil {
+		return provider{}, err
+	}
+
+	var discovery struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	err = oidcProvider.Claims(&discovery)
+	if err != nil {
+		return provider{}, err
+	}
+
+	scopes := c.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+
+	return provider{
+		conf:         c,
+		oidcProvider: oidcProvider,
+		oauthConf: &oauth2.Config{
+			ClientID:     c.ClientId,
+			ClientSecret: c.ClientSecret,
+			RedirectURL:  callbackUrl,
+			Scopes:       scopes,
+			Endpoint:     oidcProvider.Endpoint(),
+		},
+		endSessionUrl: discovery.EndSessionEndpoint,
 	}, nil
 }
 
@@ -47,57 +87,170 @@ func (s *service) SetLogger(l logger.Logger) {
 	s.log = l
 }
 
-func (s *service) AuthCodeUrl(state string) string {
-	return s.oauthConf.AuthCodeURL(state)
+func (s *service) Providers() []Provider {
+	providers := []Provider{}
+	for _, p := range s.providers {
+		providers = append(providers, Provider{
+			Id:   p.conf.Id,
+			Name: p.conf.Name,
+		})
+	}
+	return providers
+}
+
+func (s *service) provider(id string) (provider, error) {
+	for _, p := range s.providers {
+		if p.conf.Id == id {
+			return p, nil
+		}
+	}
+	return provider{}, ErrNoProvider
 }
 
-type customClaims struct {
-	Permissions []string `json:"permissions"`
-	jwt.RegisteredClaims
+// forceLogin asks the IdP to log in again even if there is a session, e.g. to link another identity.
+func (s *service) AuthCodeUrl(providerId string, state string, forceLogin bool) (string, error) {
+	p, err := s.provider(providerId)
+	if err != nil {
+		return "", err
+	}
+
+	if forceLogin {
+		return p.oauthConf.AuthCodeURL(state, oauth2.SetAuthURLParam("prompt", "login")), nil
+	}
+	return p.oauthConf.AuthCodeURL(state), nil
 }
 
-func (s *service) GetExternalUser(code string) (user.ExternalUser, error) {
-	token, err := s.oauthConf.Exchange(context.Background(), code)
+func (s *service) GetExternalUser(providerId string, code string) (user.ExternalUser, string, error) {
+	p, err := s.provider(providerId)
+	if err != nil {
+		return user.ExternalUser{}, "", err
+	}
+
+	token, err := p.oauthConf.Exchange(context.Background(), code)
 	if err != nil {
-		return user.ExternalUser{}, err
+		return user.ExternalUser{}, "", err
 	}
 
 	accessToken := token.AccessToken
 
 	rawIDToken, ok := token.Extra("id_token").(string)
 	if !ok {
-		return user.ExternalUser{}, errors.New("no id_token field in oauth2 token")
+		return user.ExternalUser{}, "", errors.New("no id_token field in oauth2 token")
 	}
 
 	oidcConfig := &oidc.Config{
-		ClientID: s.oauthConf.ClientID,
+		ClientID: p.oauthConf.ClientID,
 	}
 
-	idToken, err := s.oidcProvider.Verifier(oidcConfig).Verify(context.Background(), rawIDToken)
+	idToken, err := p.oidcProvider.Verifier(oidcConfig).Verify(context.Background(), rawIDToken)
 	if err != nil {
-		return user.ExternalUser{}, err
+		return user.ExternalUser{}, "", err
 	}
 
 	var externalUser user.ExternalUser
 	err = idToken.Claims(&externalUser)
 	if err != nil {
-		return user.ExternalUser{}, err
+		return user.ExternalUser{}, "", err
 	}
 
-	// dont verify token since this should have come from oauth and I don't want to deal with verifying right now
+	idClaims := map[string]any{}
+	err = idToken.Claims(&idClaims)
+	if err != nil {
+		return user.ExternalUser{}, "", err
+	}
+
+	// dont verify token since this should have come from oauth and I don't want to deal with verifying right now.
+	// Providers may hand out opaque access tokens, which just have no claims.
+	accessClaims := jwt.MapClaims{}
 	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256"}))
-	accessTokenParsed, _, err := parser.ParseUnverified(accessToken, &customClaims{})
+	_, _, err = parser.ParseUnverified(accessToken, accessClaims)
 	if err != nil {
-		return user.ExternalUser{}, err
+		s.log.Printf("provider %s access token is not a JWT: %v", providerId, err)
 	}
 
-	claims, ok := accessTokenParsed.Claims.(*customClaims)
-	if !ok {
-		return user.ExternalUser{}, errors.New("cannot get claims")
+	if name, ok := lookupClaim(p.nameClaim(), idClaims, accessClaims).(string); ok {
+		externalUser.FullName = name
 	}
-	externalUser.Permissions = claims.Permissions
+	externalUser.Permissions = stringsClaim(lookupClaim(p.permissionsClaim(), idClaims, accessClaims))
 
 	s.log.Printf("externalUser:%+v accessToken:%s", externalUser, accessToken)
 
-	return externalUser, nil
+	return externalUser, rawIDToken, nil
+}
+
+// Url that logs the user out of the provider too, or just the redirect url if the provider does not support it.
+func (s *service) LogoutUrl(providerId string, idToken string, redirectUrl string) (string, error) {
+	p, err := s.provider(providerId)
+	if err != nil {
+		return "", err
+	}
+
+	if p.endSessionUrl == "" {
+		return redirectUrl, nil
+	}
+
+	u, err := url.Parse(p.endSessionUrl)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	q.Set("client_id", p.oauthConf.ClientID)
+	q.Set("post_logout_redirect_uri", redirectUrl)
+	if idToken != "" {
+		q.Set("id_token_hint", idToken)
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+func (p provider) nameClaim() string {
+	if p.conf.Claims.Name == "" {
+		return "name"
+	}
+	return p.conf.Claims.Name
+}
+
+func (p provider) permissionsClaim() string {
+	if p.conf.Claims.Permissions == "" {
+		return "permissions"
+	}
+	return p.conf.Claims.Permissions
+}
+
+// Returns the value of the first set of claims that has the claim, nested claims are separated by dots.
+func lookupClaim(name string, claimSets ...map[string]any) any {
+	for _, claims := range claimSets {
+		var v any = claims
+		for _, key := range strings.Split(name, ".") {
+			m, ok := v.(map[string]any)
+			if !ok {
+				v = nil
+				break
+			}
+			v = m[key]
+		}
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// Claims can be a list of strings or, like scope, a space separated string.
+func stringsClaim(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		s := []string{}
+		for _, e := range v {
+			if e, ok := e.(string); ok {
+				s = append(s, e)
+			}
+		}
+		return s
+	}
+	return []string{}
 }
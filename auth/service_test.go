@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupClaim(t *testing.T) {
+	idClaims := map[string]any{
+		"name": "Id Name",
+		"realm_access": map[string]any{
+			"roles": []any{"modify:event", "review:user"},
+		},
+	}
+	accessClaims := map[string]any{
+		"name":        "Access Name",
+		"permissions": []any{"modify:group"},
+		"scope":       "openid profile",
+	}
+
+	assert.Equal(t, "Id Name", lookupClaim("name", idClaims, accessClaims))
+	assert.Equal(t, []string{"modify:group"}, stringsClaim(lookupClaim("permissions", idClaims, accessClaims)))
+	assert.Equal(t, []string{"modify:event", "review:user"}, stringsClaim(lookupClaim("realm_access.roles", idClaims, accessClaims)))
+	assert.Equal(t, []string{"openid", "profile"}, stringsClaim(lookupClaim("scope", idClaims, accessClaims)))
+	assert.Nil(t, lookupClaim("name.first", idClaims, accessClaims))
+	assert.Equal(t, []string{}, stringsClaim(lookupClaim("missing", idClaims, accessClaims)))
+}
+
+func TestDevService(t *testing.T) {
+	s := NewDevService()
+
+	url, err := s.AuthCodeUrl(DevProviderId, "some state", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "/auth/dev?state=some+state", url)
+
+	code, err := DevLoginCode("subject", "Dev User", []string{"review:user"})
+	assert.NoError(t, err)
+
+	eu, _, err := s.GetExternalUser(DevProviderId, code)
+	assert.NoError(t, err)
+	assert.Equal(t, DevIssuer, eu.Issuer)
+	assert.Equal(t, "subject", eu.Id)
+	assert.Equal(t, "Dev User", eu.FullName)
+	assert.Equal(t, []string{"review:user"}, eu.Permissions)
+
+	_, _, err = s.GetExternalUser("other", code)
+	assert.ErrorIs(t, err, ErrNoProvider)
+}
@@ -1,21 +1,32 @@
 package main
 
 import (
+	"context"
 	"encoding/gob"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	appPkg "github.com/mattfan00/jvbe/app"
 	"github.com/mattfan00/jvbe/auditlog"
 	"github.com/mattfan00/jvbe/auth"
+	"github.com/mattfan00/jvbe/comment"
 	"github.com/mattfan00/jvbe/config"
 	"github.com/mattfan00/jvbe/db"
 	"github.com/mattfan00/jvbe/event"
 	"github.com/mattfan00/jvbe/group"
+	"github.com/mattfan00/jvbe/ledger"
 	"github.com/mattfan00/jvbe/logger"
+	"github.com/mattfan00/jvbe/notification"
+	"github.com/mattfan00/jvbe/poll"
+	"github.com/mattfan00/jvbe/team"
 	"github.com/mattfan00/jvbe/user"
+	"github.com/mattfan00/jvbe/venue"
 
 	"github.com/alexedwards/scs/sqlite3store"
 	"github.com/alexedwards/scs/v2"
@@ -51,6 +62,10 @@ func (p *appProgram) name() string {
 func (p *appProgram) run() error {
 	log := logger.NewStdLogger()
 
+	// background jobs and the server stop once the process is asked to shut down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	conf, err := config.ReadFile(p.configPath)
 	if err != nil {
 		return err
@@ -74,14 +89,46 @@ func (p *appProgram) run() error {
 
 	userService := user.NewService(db)
 	eventService.SetLogger(log)
+	userService.SetLegacyIssuer(conf.LegacyIssuerUrl())
 
 	auditlogService := auditlog.NewService(db)
+	auditlogService.SetLogger(log)
+	if conf.AuditLog.RetentionDays > 0 {
+		go auditlogService.RunRetention(ctx, conf.AuditLog.RetentionDays, conf.AuditLogArchiveDir(), 24*time.Hour)
+	}
 
-	authService, err := auth.NewService(conf)
-	if err != nil {
-		return err
+	commentService := comment.NewService(db)
+	commentService.SetLogger(log)
+
+	notificationService := notification.NewService(db)
+	notificationService.SetLogger(log)
+
+	venueService := venue.NewService(db)
+	venueService.SetLogger(log)
+
+	pollService := poll.NewService(db)
+	pollService.SetLogger(log)
+
+	ledgerService := ledger.NewService(db)
+	ledgerService.SetLogger(log)
+
+	teamService := team.NewService(db)
+	teamService.SetLogger(log)
+
+	var authService auth.Service
+	if conf.DevAuth.Enabled {
+		log.Printf("WARNING: dev_auth is enabled, anyone can log in as any user")
+		devAuthService := auth.NewDevService()
+		devAuthService.SetLogger(log)
+		authService = devAuthService
+	} else {
+		oidcAuthService, err := auth.NewService(conf)
+		if err != nil {
+			return err
+		}
+		oidcAuthService.SetLogger(log)
+		authService = oidcAuthService
 	}
-	authService.SetLogger(log)
 
 	app := appPkg.New(
 		eventService,
@@ -89,14 +136,68 @@ func (p *appProgram) run() error {
 		authService,
 		groupService,
 		auditlogService,
+		commentService,
+		notificationService,
+		venueService,
+		pollService,
+		ledgerService,
+		teamService,
 
 		conf,
 		session,
 		log,
 	)
 
+	go purgeTrash(ctx, eventService, groupService, conf.TrashGracePeriodDays(), 24*time.Hour, log)
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", conf.Port),
+		Handler: app.Routes(),
+	}
+	go func() {
+		<-ctx.Done()
+		log.Printf("shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Errorf("shutting down server: %s", err.Error())
+		}
+	}()
+
 	log.Printf("listening on port %d", conf.Port)
-	http.ListenAndServe(fmt.Sprintf(":%d", conf.Port), app.Routes())
+	err = server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
 
-	return nil
+// Hard deletes events and groups that have been in the trash for longer than the grace period,
+// once immediately and then every interval. Blocks until ctx is done, so it is meant to be run in its own goroutine.
+func purgeTrash(
+	ctx context.Context,
+	eventService event.Service,
+	groupService group.Service,
+	gracePeriodDays int,
+	interval time.Duration,
+	log logger.Logger,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		before := db.Now().AddDate(0, 0, -gracePeriodDays)
+		if _, err := eventService.Purge(before); err != nil {
+			log.Errorf("purging events: %s", err.Error())
+		}
+		if _, err := groupService.Purge(before); err != nil {
+			log.Errorf("purging groups: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
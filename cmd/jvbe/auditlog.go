@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/mattfan00/jvbe/auditlog"
+	"github.com/mattfan00/jvbe/config"
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/logger"
+)
+
+type auditlogProgram struct {
+	fs         *flag.FlagSet
+	args       []string
+	configPath string
+}
+
+func newAuditlogProgram(args []string) *auditlogProgram {
+	fs := flag.NewFlagSet("auditlog", flag.ExitOnError)
+	p := &auditlogProgram{
+		fs:   fs,
+		args: args,
+	}
+
+	fs.StringVar(&p.configPath, "c", "./config.yaml", "path to config file")
+
+	return p
+}
+
+func (p *auditlogProgram) parse() error {
+	return p.fs.Parse(p.args)
+}
+
+func (p *auditlogProgram) name() string {
+	return p.fs.Name()
+}
+
+func (p *auditlogProgram) run() error {
+	action := p.fs.Arg(0)
+	if action == "" {
+		return errors.New("provide an action")
+	}
+
+	conf, err := config.ReadFile(p.configPath)
+	if err != nil {
+		return err
+	}
+
+	log := logger.NewStdLogger()
+	database, err := db.Connect(conf.DbConn, log)
+	if err != nil {
+		return err
+	}
+
+	auditlogService := auditlog.NewService(database)
+	auditlogService.SetLogger(log)
+
+	switch action {
+	case "verify":
+		broken, err := auditlogService.Verify()
+		if err != nil {
+			return err
+		}
+		if broken != nil {
+			return fmt.Errorf(
+				"audit log chain broken at row %d recorded at %s: %s",
+				broken.RowId,
+				broken.RecordedAt.Format("2006-01-02 15:04:05"),
+				broken.Reason,
+			)
+		}
+		log.Printf("audit log chain is intact")
+		return nil
+	case "archive":
+		if conf.AuditLog.RetentionDays <= 0 {
+			return errors.New("audit_log.retention_days must be set to archive")
+		}
+		before := db.Now().AddDate(0, 0, -conf.AuditLog.RetentionDays)
+		path, count, err := auditlogService.Archive(before, conf.AuditLogArchiveDir())
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			log.Printf("no audit log entries older than %d days", conf.AuditLog.RetentionDays)
+			return nil
+		}
+		log.Printf("archived %d entries to %s", count, path)
+		return nil
+	case "restore":
+		src := p.fs.Arg(1)
+		if src == "" {
+			return errors.New("provide an archive file")
+		}
+		table := p.fs.Arg(2)
+		if table == "" {
+			table = "audit_log_restore"
+		}
+		count, err := auditlogService.Restore(src, table)
+		if err != nil {
+			return err
+		}
+		log.Printf("restored %d entries into %s", count, table)
+		return nil
+	}
+
+	return fmt.Errorf("unknown action: %s", action)
+}
@@ -19,10 +19,12 @@ func run() error {
 	programArgs := os.Args[2:]
 	appProgram := newAppProgram(programArgs)
 	migrationProgram := newMigrationProgram(programArgs)
+	auditlogProgram := newAuditlogProgram(programArgs)
 
 	programs := []program{
 		appProgram,
 		migrationProgram,
+		auditlogProgram,
 	}
 
 	input := os.Args[1]
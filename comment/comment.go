@@ -0,0 +1,53 @@
+package comment
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type Service interface {
+	Get(string) (Comment, error)
+	List(string) ([]Comment, error)
+	Create(CreateParams) (Comment, error)
+	Update(UpdateParams) error
+	Delete(DeleteParams) error
+}
+
+type Comment struct {
+	Id           string       `db:"id"`
+	EventId      string       `db:"event_id"`
+	UserId       string       `db:"user_id"`
+	UserFullName string       `db:"user_full_name"`
+	Body         string       `db:"body"`
+	CreatedAt    time.Time    `db:"created_at"`
+	UpdatedAt    sql.NullTime `db:"updated_at"`
+	Mentions     []Mention
+}
+
+func (c Comment) IsEdited() bool {
+	return c.UpdatedAt.Valid
+}
+
+func (c Comment) HasMention(userId string) bool {
+	for _, m := range c.Mentions {
+		if m.UserId == userId {
+			return true
+		}
+	}
+	return false
+}
+
+type Mention struct {
+	CommentId    string `db:"comment_id"`
+	UserId       string `db:"user_id"`
+	UserFullName string `db:"user_full_name"`
+}
+
+var MaxBodyLength = 1000
+
+var (
+	ErrNoComment    = errors.New("no comment found")
+	ErrNotAuthor    = errors.New("only the author can edit a comment")
+	ErrCannotDelete = errors.New("only the author or an organizer can delete a comment")
+)
@@ -0,0 +1,367 @@
+package comment
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/logger"
+)
+
+type service struct {
+	db  *db.DB
+	log logger.Logger
+}
+
+func NewService(db *db.DB) *service {
+	return &service{
+		db:  db,
+		log: logger.NewNoopLogger(),
+	}
+}
+
+func (s *service) SetLogger(l logger.Logger) {
+	s.log = l
+}
+
+func (s *service) Get(id string) (Comment, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return Comment{}, err
+	}
+	defer tx.Rollback()
+
+	c, err := get(tx, id)
+	return c, err
+}
+
+// Lists the comments of an event from oldest to newest, along with who each comment mentions.
+func (s *service) List(eventId string) ([]Comment, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Comment{}, err
+	}
+	defer tx.Rollback()
+
+	comments, err := list(tx, eventId)
+	if err != nil {
+		return []Comment{}, err
+	}
+
+	mentions, err := listMentions(tx, eventId)
+	if err != nil {
+		return []Comment{}, err
+	}
+
+	for i := range comments {
+		for _, m := range mentions {
+			if m.CommentId == comments[i].Id {
+				comments[i].Mentions = append(comments[i].Mentions, m)
+			}
+		}
+	}
+
+	return comments, nil
+}
+
+type CreateParams struct {
+	EventId        string
+	UserId         string
+	Body           string
+	MentionUserIds []string
+}
+
+// Creates a comment on an event.
+// Mentioned users must have responded to the event, otherwise an error is returned.
+func (s *service) Create(p CreateParams) (Comment, error) {
+	s.log.Printf("comment Create params %+v", p)
+
+	body, err := validateBody(p.Body)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return Comment{}, err
+	}
+	defer tx.Rollback()
+
+	id, err := create(tx, p.EventId, p.UserId, body)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	for _, userId := range p.MentionUserIds {
+		if userId == p.UserId {
+			continue
+		}
+
+		ok, err := hasResponded(tx, p.EventId, userId)
+		if err != nil {
+			return Comment{}, err
+		}
+		if !ok {
+			return Comment{}, errors.New("can only mention users who responded to the event")
+		}
+
+		err = addMention(tx, id, userId)
+		if err != nil {
+			return Comment{}, err
+		}
+	}
+
+	c, err := get(tx, id)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	c.Mentions, err = listCommentMentions(tx, id)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Comment{}, err
+	}
+
+	s.log.Printf("created comment %s", id)
+	return c, nil
+}
+
+type UpdateParams struct {
+	Id     string
+	UserId string
+	Body   string
+}
+
+// Updates the body of a comment. Only the author of the comment can update it.
+func (s *service) Update(p UpdateParams) error {
+	s.log.Printf("comment Update params %+v", p)
+
+	body, err := validateBody(p.Body)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	c, err := get(tx, p.Id)
+	if err != nil {
+		return err
+	}
+
+	if c.UserId != p.UserId {
+		return ErrNotAuthor
+	}
+
+	err = update(tx, p.Id, body)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type DeleteParams struct {
+	Id     string
+	UserId string
+	// Moderators (organizers) can delete comments by anyone
+	IsModerator bool
+}
+
+func (s *service) Delete(p DeleteParams) error {
+	s.log.Printf("comment Delete params %+v", p)
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	c, err := get(tx, p.Id)
+	if err != nil {
+		return err
+	}
+
+	if c.UserId != p.UserId && !p.IsModerator {
+		return ErrCannotDelete
+	}
+
+	err = delete(tx, p.Id, p.UserId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func validateBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", errors.New("comment cannot be empty")
+	}
+	if len(body) > MaxBodyLength {
+		return "", fmt.Errorf("comment cannot be longer than %d characters", MaxBodyLength)
+	}
+
+	return body, nil
+}
+
+func get(tx *sqlx.Tx, id string) (Comment, error) {
+	stmt := `
+        SELECT
+            ec.id, ec.event_id, ec.user_id, ec.body, ec.created_at, ec.updated_at
+            , u.full_name AS user_full_name
+        FROM event_comment ec
+        INNER JOIN user u ON ec.user_id = u.id
+        WHERE ec.id = ? AND ec.is_deleted = FALSE
+    `
+	args := []any{id}
+
+	var c Comment
+	err := tx.Get(&c, stmt, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Comment{}, ErrNoComment
+	} else if err != nil {
+		return Comment{}, err
+	}
+
+	return c, nil
+}
+
+func list(tx *sqlx.Tx, eventId string) ([]Comment, error) {
+	stmt := `
+        SELECT
+            ec.id, ec.event_id, ec.user_id, ec.body, ec.created_at, ec.updated_at
+            , u.full_name AS user_full_name
+        FROM event_comment ec
+        INNER JOIN user u ON ec.user_id = u.id
+        WHERE ec.event_id = ? AND ec.is_deleted = FALSE
+        ORDER BY ec.created_at ASC
+    `
+	args := []any{eventId}
+
+	var c []Comment
+	err := tx.Select(&c, stmt, args...)
+	return c, err
+}
+
+func listMentions(tx *sqlx.Tx, eventId string) ([]Mention, error) {
+	stmt := `
+        SELECT ecm.comment_id, ecm.user_id, u.full_name AS user_full_name
+        FROM event_comment_mention ecm
+        INNER JOIN event_comment ec ON ecm.comment_id = ec.id
+        INNER JOIN user u ON ecm.user_id = u.id
+        WHERE ec.event_id = ?
+        ORDER BY u.full_name
+    `
+	args := []any{eventId}
+
+	var m []Mention
+	err := tx.Select(&m, stmt, args...)
+	return m, err
+}
+
+func listCommentMentions(tx *sqlx.Tx, commentId string) ([]Mention, error) {
+	stmt := `
+        SELECT ecm.comment_id, ecm.user_id, u.full_name AS user_full_name
+        FROM event_comment_mention ecm
+        INNER JOIN user u ON ecm.user_id = u.id
+        WHERE ecm.comment_id = ?
+        ORDER BY u.full_name
+    `
+	args := []any{commentId}
+
+	var m []Mention
+	err := tx.Select(&m, stmt, args...)
+	return m, err
+}
+
+func create(tx *sqlx.Tx, eventId string, userId string, body string) (string, error) {
+	id, err := gonanoid.New()
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `
+        INSERT INTO event_comment (id, event_id, user_id, body, created_at)
+        VALUES (?, ?, ?, ?, ?)
+    `
+	args := []any{
+		id,
+		eventId,
+		userId,
+		body,
+		db.Now(),
+	}
+
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func update(tx *sqlx.Tx, id string, body string) error {
+	stmt := `
+        UPDATE event_comment
+        SET body = ?, updated_at = ?
+        WHERE id = ?
+    `
+	args := []any{body, db.Now(), id}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
+
+func delete(tx *sqlx.Tx, id string, deletedBy string) error {
+	stmt := `
+        UPDATE event_comment
+        SET is_deleted = TRUE, deleted_by = ?
+        WHERE id = ?
+    `
+	args := []any{deletedBy, id}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
+
+func addMention(tx *sqlx.Tx, commentId string, userId string) error {
+	stmt := `
+        INSERT INTO event_comment_mention (comment_id, user_id)
+        VALUES (?, ?)
+        ON CONFLICT (comment_id, user_id) DO NOTHING
+    `
+	args := []any{commentId, userId}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
+
+func hasResponded(tx *sqlx.Tx, eventId string, userId string) (bool, error) {
+	stmt := `
+        SELECT 1 FROM event_response
+        WHERE event_id = ? AND user_id = ?
+    `
+	args := []any{eventId, userId}
+
+	var i int
+	err := tx.Get(&i, stmt, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
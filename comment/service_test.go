@@ -0,0 +1,146 @@
+package comment_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattfan00/jvbe/comment"
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/user"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreate(t *testing.T) {
+	t.Run("EmptyBodyError", func(t *testing.T) {
+		_, err := comment.NewService(nil).Create(comment.CreateParams{Body: "   "})
+		assert.Error(t, err)
+	})
+
+	t.Run("Mentions", func(t *testing.T) {
+		db := db.TestingConnect(t)
+		defer db.Close()
+		commentService := comment.NewService(db)
+		eventService := event.NewService(db)
+		userService := user.NewService(db)
+
+		u1, err := userService.Create(user.CreateParams{FullName: "one"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		u2, err := userService.Create(user.CreateParams{FullName: "two"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		u3, err := userService.Create(user.CreateParams{FullName: "three"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		eventId, err := eventService.Create(event.CreateParams{CreatorId: u1.Id, Start: time.Now().Add(24 * time.Hour), Capacity: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = eventService.HandleResponse(event.HandleResponseParams{UserId: u2.Id, Id: eventId, AttendeeCount: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := commentService.Create(comment.CreateParams{
+			EventId:        eventId,
+			UserId:         u1.Id,
+			Body:           "who has the net?",
+			MentionUserIds: []string{u2.Id},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(c.Mentions))
+		assert.True(t, c.HasMention(u2.Id))
+
+		// u3 has not responded to the event so cannot be mentioned
+		_, err = commentService.Create(comment.CreateParams{
+			EventId:        eventId,
+			UserId:         u1.Id,
+			Body:           "hi",
+			MentionUserIds: []string{u3.Id},
+		})
+		assert.Error(t, err)
+
+		comments, err := commentService.List(eventId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 1, len(comments))
+		assert.Equal(t, "one", comments[0].UserFullName)
+		assert.Equal(t, 1, len(comments[0].Mentions))
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	commentService := comment.NewService(db)
+	userService := user.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := commentService.Create(comment.CreateParams{EventId: "event", UserId: u1.Id, Body: "before"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = commentService.Update(comment.UpdateParams{Id: c.Id, UserId: u2.Id, Body: "after"})
+	assert.ErrorIs(t, err, comment.ErrNotAuthor)
+
+	err = commentService.Update(comment.UpdateParams{Id: c.Id, UserId: u1.Id, Body: "after"})
+	assert.NoError(t, err)
+
+	c, err = commentService.Get(c.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "after", c.Body)
+	assert.True(t, c.IsEdited())
+}
+
+func TestDelete(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	commentService := comment.NewService(db)
+	userService := user.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := commentService.Create(comment.CreateParams{EventId: "event", UserId: u1.Id, Body: "body"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = commentService.Delete(comment.DeleteParams{Id: c.Id, UserId: u2.Id})
+	assert.ErrorIs(t, err, comment.ErrCannotDelete)
+
+	// organizers can delete anyone's comment
+	err = commentService.Delete(comment.DeleteParams{Id: c.Id, UserId: u2.Id, IsModerator: true})
+	assert.NoError(t, err)
+
+	comments, err := commentService.List("event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(comments))
+}
@@ -7,28 +7,138 @@ import (
 )
 
 type Oauth struct {
-	Domain            string `yaml:"domain"`
-	ClientId          string `yaml:"client_id"`
-	ClientSecret      string `yaml:"client_secret"`
-	CallbackUrl       string `yaml:"callback_url"`
-	LogoutRedirectUrl string `yaml:"logout_redirect_url"`
+	// single provider at https://domain, only used when no providers are configured
+	Domain       string `yaml:"domain"`
+	ClientId     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+
+	Providers         []OidcProvider `yaml:"providers"`
+	CallbackUrl       string         `yaml:"callback_url"`
+	LogoutRedirectUrl string         `yaml:"logout_redirect_url"`
+}
+
+// An OpenID Connect provider users can log in with, its endpoints are discovered from the issuer url.
+type OidcProvider struct {
+	Id           string     `yaml:"id"`   // part of the login url, /auth/login/{id}
+	Name         string     `yaml:"name"` // shown on the login page
+	IssuerUrl    string     `yaml:"issuer_url"`
+	ClientId     string     `yaml:"client_id"`
+	ClientSecret string     `yaml:"client_secret"`
+	Scopes       []string   `yaml:"scopes"` // defaults to openid, profile and email
+	Claims       OidcClaims `yaml:"claims"`
+	// the provider configured with oauth.domain before providers could be listed, its logins take over users from back then
+	Legacy bool `yaml:"legacy"`
+}
+
+// Claims the user is read from, looked up in the ID token and then in the access token if it is a JWT.
+// Nested claims are separated by dots, e.g. realm_access.roles.
+type OidcClaims struct {
+	Name        string `yaml:"name"`        // defaults to name
+	Permissions string `yaml:"permissions"` // defaults to permissions
+}
+
+type AuditLog struct {
+	RetentionDays int    `yaml:"retention_days"` // 0 keeps entries forever
+	ArchiveDir    string `yaml:"archive_dir"`
+}
+
+type Trash struct {
+	GracePeriodDays int `yaml:"grace_period_days"`
+}
+
+// New users matching any of these are approved without waiting for a reviewer.
+type AutoApprove struct {
+	InviteGroupIds []string `yaml:"invite_group_ids"`
+	EmailDomains   []string `yaml:"email_domains"`
+	Vouch          bool     `yaml:"vouch"`
+}
+
+type Review struct {
+	RequiredVouches int `yaml:"required_vouches"` // 0 lets reviewers approve without any vouches
+}
+
+// Replaces the oauth providers with a login form to pick or create any user, for running locally without credentials.
+// Never enable this on a deployed instance since anyone can log in as anyone.
+type DevAuth struct {
+	Enabled bool `yaml:"enabled"`
 }
 
 type Config struct {
-	DbConn  string `yaml:"db_conn"`
-	Port    int    `yaml:"port"`
-	BaseUrl string `yaml:"base_url"`
-	Oauth   Oauth  `yaml:"oauth"`
+	DbConn   string   `yaml:"db_conn"`
+	Port     int      `yaml:"port"`
+	BaseUrl  string   `yaml:"base_url"`
+	Oauth    Oauth    `yaml:"oauth"`
+	DevAuth  DevAuth  `yaml:"dev_auth"`
+	AuditLog AuditLog `yaml:"audit_log"`
+	Trash    Trash    `yaml:"trash"`
+	Timezone string   `yaml:"timezone"`
+
+	AutoApprove AutoApprove `yaml:"auto_approve"`
+	Review      Review      `yaml:"review"`
 }
 
 func (c Config) OauthLogoutRedirectUrl() string {
 	return c.BaseUrl + c.Oauth.LogoutRedirectUrl
 }
 
+// Falls back to the single provider at oauth.domain when no providers are configured.
+func (c Config) OidcProviders() []OidcProvider {
+	if len(c.Oauth.Providers) > 0 {
+		return c.Oauth.Providers
+	}
+	if c.Oauth.Domain == "" {
+		return []OidcProvider{}
+	}
+
+	return []OidcProvider{
+		{
+			Id:           "default",
+			Name:         c.Oauth.Domain,
+			IssuerUrl:    "https://" + c.Oauth.Domain,
+			ClientId:     c.Oauth.ClientId,
+			ClientSecret: c.Oauth.ClientSecret,
+			Legacy:       true,
+		},
+	}
+}
+
+// Issuer of the provider users logged in with before issuers were recorded, empty if there is none.
+func (c Config) LegacyIssuerUrl() string {
+	for _, p := range c.OidcProviders() {
+		if p.Legacy {
+			return p.IssuerUrl
+		}
+	}
+	return ""
+}
+
 func (c Config) OauthCallbackUrl() string {
 	return c.BaseUrl + c.Oauth.CallbackUrl
 }
 
+func (c Config) AuditLogArchiveDir() string {
+	if c.AuditLog.ArchiveDir == "" {
+		return "./archive"
+	}
+	return c.AuditLog.ArchiveDir
+}
+
+// Number of days deleted events and groups can be restored before they are purged.
+func (c Config) TrashGracePeriodDays() int {
+	if c.Trash.GracePeriodDays <= 0 {
+		return 30
+	}
+	return c.Trash.GracePeriodDays
+}
+
+// IANA timezone used for users, events and venues that have not set one.
+func (c Config) DefaultTimezone() string {
+	if c.Timezone == "" {
+		return "UTC"
+	}
+	return c.Timezone
+}
+
 func ReadFile(src string) (*Config, error) {
 	b, err := os.ReadFile(src)
 	if err != nil {
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- rows recorded before this migration keep an empty hash and are treated as unchained
+ALTER TABLE audit_log
+ADD COLUMN prev_hash TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE audit_log
+ADD COLUMN hash TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE audit_log DROP COLUMN prev_hash;
+
+ALTER TABLE audit_log DROP COLUMN hash;
+-- +goose StatementEnd
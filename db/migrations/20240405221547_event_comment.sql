@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS event_comment (
+    id TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME,
+    is_deleted BOOL NOT NULL DEFAULT 0,
+    deleted_by TEXT
+);
+
+CREATE INDEX IF NOT EXISTS event_comment_event_id_idx ON event_comment(event_id);
+
+CREATE TABLE IF NOT EXISTS event_comment_mention (
+    comment_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS notification (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    message TEXT NOT NULL,
+    link TEXT NOT NULL,
+    read_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS notification_user_id_idx ON notification(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS event_comment;
+DROP INDEX IF EXISTS event_comment_event_id_idx;
+DROP TABLE IF EXISTS event_comment_mention;
+DROP TABLE IF EXISTS notification;
+DROP INDEX IF EXISTS notification_user_id_idx;
+-- +goose StatementEnd
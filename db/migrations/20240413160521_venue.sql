@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS venue (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL DEFAULT '',
+    map_url TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    default_capacity INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    creator_id TEXT NOT NULL,
+    is_deleted BOOL NOT NULL DEFAULT 0
+);
+
+ALTER TABLE event
+ADD COLUMN venue_id TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS venue;
+
+ALTER TABLE event DROP COLUMN venue_id;
+-- +goose StatementEnd
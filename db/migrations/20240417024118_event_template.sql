@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS event_template (
+    id TEXT PRIMARY KEY,
+    template_name TEXT NOT NULL,
+    name TEXT NOT NULL,
+    group_id TEXT,
+    capacity INTEGER NOT NULL,
+    location TEXT NOT NULL,
+    venue_id TEXT,
+    description TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    creator_id TEXT NOT NULL,
+    is_deleted BOOL NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS event_invitation (
+    event_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    invited_by TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (event_id, user_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS event_template;
+DROP TABLE IF EXISTS event_invitation;
+-- +goose StatementEnd
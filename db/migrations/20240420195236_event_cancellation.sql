@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE event
+ADD COLUMN cancelled_at DATETIME;
+
+ALTER TABLE event
+ADD COLUMN cancelled_by TEXT;
+
+ALTER TABLE event
+ADD COLUMN cancel_reason TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE event DROP COLUMN cancelled_at;
+
+ALTER TABLE event DROP COLUMN cancelled_by;
+
+ALTER TABLE event DROP COLUMN cancel_reason;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE event
+ADD COLUMN deleted_at DATETIME;
+
+ALTER TABLE event
+ADD COLUMN deleted_by TEXT;
+
+ALTER TABLE user_group
+ADD COLUMN deleted_at DATETIME;
+
+ALTER TABLE user_group
+ADD COLUMN deleted_by TEXT;
+
+-- anything deleted before the trash existed starts its grace period now
+UPDATE event SET deleted_at = CURRENT_TIMESTAMP WHERE is_deleted = TRUE;
+
+UPDATE user_group SET deleted_at = CURRENT_TIMESTAMP WHERE is_deleted = TRUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE event DROP COLUMN deleted_at;
+
+ALTER TABLE event DROP COLUMN deleted_by;
+
+ALTER TABLE user_group DROP COLUMN deleted_at;
+
+ALTER TABLE user_group DROP COLUMN deleted_by;
+-- +goose StatementEnd
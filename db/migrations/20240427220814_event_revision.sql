@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS event_revision (
+    id TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS event_revision_event_id_idx ON event_revision(event_id);
+
+CREATE TABLE IF NOT EXISTS event_revision_change (
+    revision_id TEXT NOT NULL,
+    field TEXT NOT NULL,
+    old_value TEXT NOT NULL,
+    new_value TEXT NOT NULL,
+    PRIMARY KEY (revision_id, field)
+);
+
+-- responses whose waitlist status was changed as a result of the revision
+CREATE TABLE IF NOT EXISTS event_revision_response (
+    revision_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    on_waitlist BOOL NOT NULL,
+    PRIMARY KEY (revision_id, user_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS event_revision;
+DROP INDEX IF EXISTS event_revision_event_id_idx;
+DROP TABLE IF EXISTS event_revision_change;
+DROP TABLE IF EXISTS event_revision_response;
+-- +goose StatementEnd
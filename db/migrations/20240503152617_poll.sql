@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS poll (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    group_id TEXT,
+    capacity INT NOT NULL DEFAULT 0,
+    location TEXT NOT NULL DEFAULT '',
+    venue_id TEXT,
+    description TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    creator_id TEXT NOT NULL,
+    closed_at DATETIME,
+    event_id TEXT
+);
+
+CREATE TABLE IF NOT EXISTS poll_option (
+    id TEXT PRIMARY KEY,
+    poll_id TEXT NOT NULL,
+    start DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS poll_option_poll_id_idx ON poll_option(poll_id);
+
+CREATE TABLE IF NOT EXISTS poll_vote (
+    option_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (option_id, user_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS poll;
+DROP TABLE IF EXISTS poll_option;
+DROP INDEX IF EXISTS poll_option_poll_id_idx;
+DROP TABLE IF EXISTS poll_vote;
+-- +goose StatementEnd
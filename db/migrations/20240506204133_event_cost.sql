@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE event ADD COLUMN cost INT NOT NULL DEFAULT 0;
+
+-- payments are recorded per attendee, what each attendee owes is computed from the event cost
+CREATE TABLE IF NOT EXISTS event_payment (
+    event_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    amount INT NOT NULL,
+    paid_at DATETIME NOT NULL,
+    marked_by TEXT NOT NULL,
+    PRIMARY KEY (event_id, user_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE event DROP COLUMN cost;
+DROP TABLE IF EXISTS event_payment;
+-- +goose StatementEnd
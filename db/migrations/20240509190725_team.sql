@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS skill_rating (
+    group_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    rating INT NOT NULL,
+    updated_at DATETIME NOT NULL,
+    updated_by TEXT NOT NULL,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS event_team (
+    id TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    position INT NOT NULL,
+    created_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS event_team_event_id_idx ON event_team(event_id);
+
+CREATE TABLE IF NOT EXISTS event_team_member (
+    team_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    attendee_count INT NOT NULL,
+    PRIMARY KEY (team_id, user_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS skill_rating;
+DROP TABLE IF EXISTS event_team;
+DROP INDEX IF EXISTS event_team_event_id_idx;
+DROP TABLE IF EXISTS event_team_member;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS event_match (
+    id TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL,
+    home_team_id TEXT NOT NULL,
+    away_team_id TEXT NOT NULL,
+    home_score INT NOT NULL,
+    away_score INT NOT NULL,
+    created_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS event_match_event_id_idx ON event_match(event_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS event_match;
+DROP INDEX IF EXISTS event_match_event_id_idx;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS event_tag (
+    event_id TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (event_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS event_tag_tag_idx ON event_tag(tag);
+
+CREATE TABLE IF NOT EXISTS tag_follow (
+    user_id TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, tag)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS event_tag;
+DROP INDEX IF EXISTS event_tag_tag_idx;
+DROP TABLE IF EXISTS tag_follow;
+-- +goose StatementEnd
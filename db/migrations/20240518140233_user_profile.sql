@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE user ADD COLUMN bio TEXT NOT NULL DEFAULT '';
+ALTER TABLE user ADD COLUMN contact_info TEXT NOT NULL DEFAULT '';
+ALTER TABLE user ADD COLUMN contact_visibility INT NOT NULL DEFAULT 0;
+
+-- the name from the IdP is kept separately so the user can go back to it after overriding it
+ALTER TABLE user ADD COLUMN external_full_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE user ADD COLUMN full_name_overridden BOOL NOT NULL DEFAULT 0;
+UPDATE user SET external_full_name = full_name;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user DROP COLUMN bio;
+ALTER TABLE user DROP COLUMN contact_info;
+ALTER TABLE user DROP COLUMN contact_visibility;
+ALTER TABLE user DROP COLUMN external_full_name;
+ALTER TABLE user DROP COLUMN full_name_overridden;
+-- +goose StatementEnd
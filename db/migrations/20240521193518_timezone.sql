@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- IANA timezone names, empty means the default timezone from the config
+ALTER TABLE user ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE venue ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE event ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE poll ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user DROP COLUMN timezone;
+ALTER TABLE venue DROP COLUMN timezone;
+ALTER TABLE event DROP COLUMN timezone;
+ALTER TABLE poll DROP COLUMN timezone;
+-- +goose StatementEnd
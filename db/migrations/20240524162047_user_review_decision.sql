@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- who approved, rejected or suspended the user last and why
+ALTER TABLE user_review ADD COLUMN reviewer_id TEXT;
+ALTER TABLE user_review ADD COLUMN reviewer_comment TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user_review DROP COLUMN reviewer_id;
+ALTER TABLE user_review DROP COLUMN reviewer_comment;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the auto-approval rule that approved the user, NULL when a reviewer decided
+ALTER TABLE user_review ADD COLUMN approval_rule TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user_review DROP COLUMN approval_rule;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_vouch (
+    user_id TEXT NOT NULL,
+    voucher_id TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    confirmed_at DATETIME,
+    PRIMARY KEY (user_id, voucher_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_vouch;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- set on users merged into another user, logging in as them continues as that user
+ALTER TABLE user ADD COLUMN merged_into_id TEXT;
+-- user an audit log entry was recorded by before it was moved by a merge, the hash is computed with it
+ALTER TABLE audit_log ADD COLUMN recorded_user_id TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user DROP COLUMN merged_into_id;
+ALTER TABLE audit_log DROP COLUMN recorded_user_id;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- external subjects a user can log in as, a user can link several
+CREATE TABLE IF NOT EXISTS user_identity (
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identity_user_id_idx ON user_identity(user_id);
+
+-- the issuer of existing logins is not known, it is filled in on their next login
+INSERT INTO user_identity (issuer, subject, user_id, created_at)
+SELECT '', external_id, COALESCE(merged_into_id, id), created_at
+FROM user
+WHERE external_id != '' AND external_id NOT LIKE 'deleted:%';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS user_identity_user_id_idx;
+DROP TABLE IF EXISTS user_identity;
+-- +goose StatementEnd
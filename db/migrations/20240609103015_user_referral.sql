@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- secret token in a member's referral link, so that links cannot be made up from public user ids
+CREATE TABLE IF NOT EXISTS user_referral (
+    user_id TEXT PRIMARY KEY,
+    token TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_referral;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE event_template ADD COLUMN cost INT NOT NULL DEFAULT 0;
+ALTER TABLE event_template ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS event_template_tag (
+    template_id TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (template_id, tag)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE event_template DROP COLUMN cost;
+ALTER TABLE event_template DROP COLUMN timezone;
+DROP TABLE IF EXISTS event_template_tag;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- single row that pins down where the audit log hash chain starts
+CREATE TABLE IF NOT EXISTS audit_log_anchor (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    -- entries up to this row were recorded before the hash chain and are the only ones allowed without a hash
+    legacy_row_id INTEGER NOT NULL,
+    -- hash of the last archived entry, the first entry left in audit_log has to chain onto it
+    hash TEXT NOT NULL DEFAULT ''
+);
+
+-- entries archived before the anchor existed are gone, so the first chained entry left is trusted once here
+INSERT INTO audit_log_anchor (id, legacy_row_id, hash)
+VALUES (
+    1,
+    COALESCE(
+        (SELECT MIN(rowid) - 1 FROM audit_log WHERE hash <> ''),
+        (SELECT MAX(rowid) FROM audit_log),
+        0
+    ),
+    COALESCE(
+        (SELECT prev_hash FROM audit_log WHERE hash <> '' ORDER BY rowid LIMIT 1),
+        ''
+    )
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_log_anchor;
+-- +goose StatementEnd
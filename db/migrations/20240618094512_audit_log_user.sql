@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- entries keep the user they were recorded by, merged users are resolved through user.merged_into_id instead
+UPDATE audit_log SET user_id = recorded_user_id WHERE recorded_user_id <> '';
+ALTER TABLE audit_log DROP COLUMN recorded_user_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE audit_log ADD COLUMN recorded_user_id TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- option the poll was converted into an event with, set when the poll is closed
+ALTER TABLE poll ADD COLUMN option_id TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE poll DROP COLUMN option_id;
+-- +goose StatementEnd
@@ -2,6 +2,8 @@ package event
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -9,11 +11,29 @@ type Service interface {
 	Get(string) (Event, error)
 	GetDetailed(string, string) (EventDetailed, error)
 	ListResponses(string) ([]EventResponse, error)
+	ListUserResponses(string) ([]UserResponse, error)
+	WithdrawUpcomingResponses(string) ([]WithdrawnResponse, error)
 	List(ListFilter) (EventList, error)
 	Create(CreateParams) (string, error)
-	Update(UpdateParams) error
-	Delete(string) error
+	Update(UpdateParams) ([]EventResponse, error)
+	PreviewUpdate(UpdateParams) ([]EventResponse, error)
+	Delete(string, string) error
+	ListDeleted() ([]Event, error)
+	Restore(string) error
+	Purge(time.Time) (int, error)
+	Cancel(CancelParams) error
 	HandleResponse(HandleResponseParams) error
+	Duplicate(DuplicateParams) (string, error)
+	ListInvitations(string) ([]EventInvitation, error)
+	GetTemplate(string) (EventTemplate, error)
+	ListTemplates() ([]EventTemplate, error)
+	CreateTemplate(CreateTemplateParams) (string, error)
+	DeleteTemplate(string) error
+	ListTags() ([]string, error)
+	ListFollowedTags(string) ([]string, error)
+	ListTagFollowers([]string) ([]string, error)
+	FollowTag(string, string) error
+	UnfollowTag(string, string) error
 }
 
 type Event struct {
@@ -23,18 +43,64 @@ type Event struct {
 	GroupName          sql.NullString `db:"group_name"`
 	Capacity           int            `db:"capacity"`
 	Start              time.Time      `db:"start"`
-	Location           string         `db:"location"`
+	Location           string         `db:"location"` // free text fallback when there is no venue
+	VenueId            sql.NullString `db:"venue_id"`
+	VenueName          sql.NullString `db:"venue_name"`
+	VenueAddress       sql.NullString `db:"venue_address"`
+	VenueMapUrl        sql.NullString `db:"venue_map_url"`
+	VenueNotes         sql.NullString `db:"venue_notes"`
+	Description        string         `db:"description"` // markdown
+	Cost               int            `db:"cost"`        // in cents, split between attendees
+	Timezone           string         `db:"timezone"`    // IANA timezone the event is scheduled in, empty for the default
+	Tags               []string
 	CreatedAt          time.Time      `db:"created_at"`
 	CreatorId          string         `db:"creator_id"`
 	CreatorFullName    string         `db:"creator_full_name"`
 	TotalAttendeeCount int            `db:"total_attendee_count"`
 	IsPast             bool           `db:"is_past"`
+	CancelledAt        sql.NullTime   `db:"cancelled_at"`
+	CancelReason       string         `db:"cancel_reason"`
+	DeletedAt          sql.NullTime   `db:"deleted_at"`
+	DeletedByFullName  sql.NullString `db:"deleted_by_full_name"`
+}
+
+// Cancelled events stay visible but can no longer be responded to.
+// This is different from deleting, which hides the event entirely.
+func (e Event) IsCancelled() bool {
+	return e.CancelledAt.Valid
+}
+
+// Prefers the venue name, falling back to the free text location.
+func (e Event) DisplayLocation() string {
+	if e.VenueName.Valid {
+		return e.VenueName.String
+	}
+	return e.Location
+}
+
+func (e Event) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
 func (e Event) SpotsLeft() int {
 	return e.Capacity - e.TotalAttendeeCount
 }
 
+// Formats an amount in cents as a decimal, e.g. 1250 as "12.50".
+func FormatCents(c int) string {
+	sign := ""
+	if c < 0 {
+		sign = "-"
+		c = -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
+
 type EventResponse struct {
 	EventId       string    `db:"event_id"`
 	UserId        string    `db:"user_id"`
@@ -42,17 +108,61 @@ type EventResponse struct {
 	UpdatedAt     time.Time `db:"updated_at"`
 	AttendeeCount int       `db:"attendee_count"`
 	OnWaitlist    bool      `db:"on_waitlist"`
+	IsProtected   bool      `db:"is_protected"` // kept off the waitlist ahead of earlier responders
 	UserFullName  string    `db:"user_full_name"`
 }
 
+// A response of a single user along with the event it is for.
+type UserResponse struct {
+	EventId          string         `db:"event_id"`
+	EventName        string         `db:"event_name"`
+	EventStart       time.Time      `db:"event_start"`
+	EventGroupId     sql.NullString `db:"event_group_id"`
+	EventCancelledAt sql.NullTime   `db:"event_cancelled_at"`
+	IsPast           bool           `db:"is_past"`
+	AttendeeCount    int            `db:"attendee_count"`
+	OnWaitlist       bool           `db:"on_waitlist"`
+}
+
+func (r UserResponse) IsCancelled() bool {
+	return r.EventCancelledAt.Valid
+}
+
+// A response removed from an upcoming event, e.g. when the user is suspended.
+type WithdrawnResponse struct {
+	EventId   string
+	EventName string
+	// responses that had their waitlist status changed by the withdrawal
+	Moved []EventResponse
+}
+
+func (r UserResponse) PlusOnes() int {
+	return r.AttendeeCount - 1
+}
+
+// Whether the user showed up, meaning the event happened and they were not on the waitlist.
+func (r UserResponse) Attended() bool {
+	return r.IsPast && !r.IsCancelled() && !r.OnWaitlist
+}
+
 func (e EventResponse) PlusOnes() int {
 	return e.AttendeeCount - 1
 }
 
+type EventInvitation struct {
+	EventId      string    `db:"event_id"`
+	UserId       string    `db:"user_id"`
+	UserFullName string    `db:"user_full_name"`
+	InvitedBy    string    `db:"invited_by"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
 type EventDetailed struct {
 	Event
 	UserResponse *EventResponse
 	Responses    []EventResponse
+	IsInvited    bool
+	Revisions    []EventRevision
 }
 
 // containing this in a struct in case need to include more fields for pagination
@@ -61,3 +171,10 @@ type EventList struct {
 }
 
 var MaxAttendeeCount = 2
+
+var MaxDescriptionLength = 5000
+
+var (
+	ErrCancelled    = errors.New("event has been cancelled")
+	ErrNegativeCost = errors.New("cost cannot be negative")
+)
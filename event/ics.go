@@ -0,0 +1,75 @@
+package event
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const icsTimeFormat = "20060102T150405Z"
+
+// Writes the event as an iCalendar file so it can be added to a calendar app.
+// The description is included as plain markdown since calendar apps do not render it.
+// Cancelled events are marked as such, along with the reason, so calendars that imported them can show it.
+func WriteICS(w io.Writer, e Event, url string) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//jvbe//events//EN",
+		"BEGIN:VEVENT",
+		"UID:" + e.Id + "@jvbe",
+		"DTSTAMP:" + time.Now().UTC().Format(icsTimeFormat),
+		"DTSTART:" + e.Start.UTC().Format(icsTimeFormat),
+		"SUMMARY:" + escapeICSText(e.Name),
+		"LOCATION:" + escapeICSText(e.DisplayLocation()),
+	}
+	description := e.Description
+	if e.IsCancelled() {
+		lines = append(lines, "STATUS:CANCELLED")
+		description = strings.TrimSpace("Cancelled: " + e.CancelReason + "\n\n" + description)
+	}
+	if description != "" {
+		lines = append(lines, "DESCRIPTION:"+escapeICSText(description))
+	}
+	if url != "" {
+		lines = append(lines, "URL:"+url)
+	}
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+
+	for _, l := range lines {
+		if _, err := fmt.Fprint(w, foldICSLine(l)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var icsTextReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+func escapeICSText(s string) string {
+	return icsTextReplacer.Replace(s)
+}
+
+// Lines longer than 75 bytes are split, continuation lines start with a space.
+// Splits are only made between runes so multi-byte characters stay intact.
+func foldICSLine(l string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range l {
+		size := len(string(r))
+		if n+size > 75 {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
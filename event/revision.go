@@ -0,0 +1,187 @@
+package event
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+// Record of a single application of UpdateParams to an event.
+type EventRevision struct {
+	Id           string    `db:"id"`
+	EventId      string    `db:"event_id"`
+	UserId       string    `db:"user_id"`
+	UserFullName string    `db:"user_full_name"`
+	CreatedAt    time.Time `db:"created_at"`
+	Changes      []EventRevisionChange
+	// Responses that were moved on or off the waitlist by the update
+	MovedResponses []EventRevisionResponse
+}
+
+type EventRevisionChange struct {
+	RevisionId string `db:"revision_id"`
+	Field      string `db:"field"`
+	OldValue   string `db:"old_value"`
+	NewValue   string `db:"new_value"`
+}
+
+// Start values are stored in RFC 3339 so they can be formatted in the viewer's time zone
+func (c EventRevisionChange) IsTime() bool {
+	return c.Field == "start"
+}
+
+func (c EventRevisionChange) OldTime() time.Time {
+	t, _ := time.Parse(time.RFC3339, c.OldValue)
+	return t
+}
+
+func (c EventRevisionChange) NewTime() time.Time {
+	t, _ := time.Parse(time.RFC3339, c.NewValue)
+	return t
+}
+
+type EventRevisionResponse struct {
+	RevisionId   string `db:"revision_id"`
+	UserId       string `db:"user_id"`
+	UserFullName string `db:"user_full_name"`
+	OnWaitlist   bool   `db:"on_waitlist"`
+}
+
+// Compares the fields that can be changed through UpdateParams.
+func diffEvents(before Event, after Event) []EventRevisionChange {
+	changes := []EventRevisionChange{}
+	add := func(field string, old string, new string) {
+		if old != new {
+			changes = append(changes, EventRevisionChange{
+				Field:    field,
+				OldValue: old,
+				NewValue: new,
+			})
+		}
+	}
+
+	add("name", before.Name, after.Name)
+	add("capacity", strconv.Itoa(before.Capacity), strconv.Itoa(after.Capacity))
+	add("start", before.Start.UTC().Format(time.RFC3339), after.Start.UTC().Format(time.RFC3339))
+	add("timezone", before.Timezone, after.Timezone)
+	add("location", before.DisplayLocation(), after.DisplayLocation())
+	add("description", before.Description, after.Description)
+	add("cost", FormatCents(before.Cost), FormatCents(after.Cost))
+	add("tags", strings.Join(before.Tags, ", "), strings.Join(after.Tags, ", "))
+
+	return changes
+}
+
+// Saves a revision if anything changed or any responses were moved.
+func createRevision(
+	tx *sqlx.Tx,
+	eventId string,
+	userId string,
+	changes []EventRevisionChange,
+	moved []EventResponse,
+) error {
+	if len(changes) == 0 && len(moved) == 0 {
+		return nil
+	}
+
+	id, err := gonanoid.New()
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+        INSERT INTO event_revision (id, event_id, user_id, created_at)
+        VALUES (?, ?, ?, ?)
+    `
+	args := []any{
+		id,
+		eventId,
+		userId,
+		time.Now().UTC(),
+	}
+	if _, err := tx.Exec(stmt, args...); err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		stmt := `
+            INSERT INTO event_revision_change (revision_id, field, old_value, new_value)
+            VALUES (?, ?, ?, ?)
+        `
+		args := []any{id, c.Field, c.OldValue, c.NewValue}
+		if _, err := tx.Exec(stmt, args...); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range moved {
+		stmt := `
+            INSERT INTO event_revision_response (revision_id, user_id, on_waitlist)
+            VALUES (?, ?, ?)
+        `
+		args := []any{id, r.UserId, r.OnWaitlist}
+		if _, err := tx.Exec(stmt, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Lists revisions of an event, newest first.
+func listRevisions(tx *sqlx.Tx, eventId string) ([]EventRevision, error) {
+	stmt := `
+        SELECT er.id, er.event_id, er.user_id, er.created_at, u.full_name AS user_full_name
+        FROM event_revision AS er
+        INNER JOIN user AS u ON er.user_id = u.id
+        WHERE er.event_id = ?
+        ORDER BY er.created_at DESC
+    `
+	var revisions []EventRevision
+	if err := tx.Select(&revisions, stmt, eventId); err != nil {
+		return []EventRevision{}, err
+	}
+
+	stmt = `
+        SELECT erc.revision_id, erc.field, erc.old_value, erc.new_value
+        FROM event_revision_change AS erc
+        INNER JOIN event_revision AS er ON erc.revision_id = er.id
+        WHERE er.event_id = ?
+        ORDER BY erc.rowid
+    `
+	var changes []EventRevisionChange
+	if err := tx.Select(&changes, stmt, eventId); err != nil {
+		return []EventRevision{}, err
+	}
+
+	stmt = `
+        SELECT err.revision_id, err.user_id, err.on_waitlist, u.full_name AS user_full_name
+        FROM event_revision_response AS err
+        INNER JOIN event_revision AS er ON err.revision_id = er.id
+        INNER JOIN user AS u ON err.user_id = u.id
+        WHERE er.event_id = ?
+        ORDER BY u.full_name
+    `
+	var moved []EventRevisionResponse
+	if err := tx.Select(&moved, stmt, eventId); err != nil {
+		return []EventRevision{}, err
+	}
+
+	for i := range revisions {
+		for _, c := range changes {
+			if c.RevisionId == revisions[i].Id {
+				revisions[i].Changes = append(revisions[i].Changes, c)
+			}
+		}
+		for _, m := range moved {
+			if m.RevisionId == revisions[i].Id {
+				revisions[i].MovedResponses = append(revisions[i].MovedResponses, m)
+			}
+		}
+	}
+
+	return revisions, nil
+}
@@ -62,10 +62,22 @@ func (s *service) GetDetailed(id string, userId string) (EventDetailed, error) {
 		return EventDetailed{}, err
 	}
 
+	isInvited, err := hasInvitation(tx, id, userId)
+	if err != nil {
+		return EventDetailed{}, err
+	}
+
+	revisions, err := listRevisions(tx, id)
+	if err != nil {
+		return EventDetailed{}, err
+	}
+
 	ed := EventDetailed{
 		Event:        e,
 		Responses:    r,
 		UserResponse: ur,
+		IsInvited:    isInvited,
+		Revisions:    revisions,
 	}
 
 	return ed, nil
@@ -82,10 +94,108 @@ func (s *service) ListResponses(eventId string) ([]EventResponse, error) {
 	return el, err
 }
 
+// Lists every response of the user, the latest events first.
+func (s *service) ListUserResponses(userId string) ([]UserResponse, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []UserResponse{}, err
+	}
+	defer tx.Rollback()
+
+	stmt := `
+        SELECT
+            e.id AS event_id, e.name AS event_name, e.start AS event_start
+            , e.group_id AS event_group_id, e.cancelled_at AS event_cancelled_at
+            , CASE
+                WHEN datetime() > datetime(e.start) THEN TRUE
+                ELSE FALSE
+            END AS is_past
+            , er.attendee_count, er.on_waitlist
+        FROM event_response AS er
+        INNER JOIN event AS e ON er.event_id = e.id
+        WHERE er.user_id = ? AND e.is_deleted = FALSE
+        ORDER BY e.start DESC
+    `
+	args := []any{userId}
+
+	responses := []UserResponse{}
+	err = tx.Select(&responses, stmt, args...)
+	return responses, err
+}
+
+// Removes the user's responses to upcoming events that are not cancelled, letting the waitlist move up.
+func (s *service) WithdrawUpcomingResponses(userId string) ([]WithdrawnResponse, error) {
+	s.log.Printf("event WithdrawUpcomingResponses user %s", userId)
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []WithdrawnResponse{}, err
+	}
+	defer tx.Rollback()
+
+	withdrawn, err := WithdrawUpcomingResponsesTx(tx, userId)
+	if err != nil {
+		return []WithdrawnResponse{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return []WithdrawnResponse{}, err
+	}
+
+	return withdrawn, nil
+}
+
+// Same as WithdrawUpcomingResponses, for services that remove a user as part of their own transaction.
+func WithdrawUpcomingResponsesTx(tx *sqlx.Tx, userId string) ([]WithdrawnResponse, error) {
+	stmt := `
+        SELECT e.id, e.name
+        FROM event_response AS er
+        INNER JOIN event AS e ON er.event_id = e.id
+        WHERE er.user_id = ?
+            AND e.is_deleted = FALSE
+            AND e.cancelled_at IS NULL
+            AND datetime() <= datetime(e.start)
+        ORDER BY e.start
+    `
+	var events []Event
+	err := tx.Select(&events, stmt, userId)
+	if err != nil {
+		return []WithdrawnResponse{}, err
+	}
+
+	withdrawn := []WithdrawnResponse{}
+	for _, e := range events {
+		err = deleteResponse(tx, e.Id, userId)
+		if err != nil {
+			return []WithdrawnResponse{}, err
+		}
+
+		moved, err := manageWaitlist(tx, e.Id)
+		if err != nil {
+			return []WithdrawnResponse{}, err
+		}
+
+		withdrawn = append(withdrawn, WithdrawnResponse{
+			EventId:   e.Id,
+			EventName: e.Name,
+			Moved:     moved,
+		})
+	}
+
+	return withdrawn, nil
+}
+
+// Moves responses on and off the waitlist after they were changed outside of this service, e.g. by merging users.
+// Runs in the caller's transaction so the change and the waitlist are committed together.
+func UpdateWaitlistTx(tx *sqlx.Tx, id string) ([]EventResponse, error) {
+	return manageWaitlist(tx, id)
+}
+
 type ListFilter struct {
 	UserId      string
 	Upcoming    bool
 	Past        bool
+	Tag         string
 	Limit       int
 	Offset      int
 	OrderByDesc bool
@@ -103,16 +213,26 @@ func (s *service) List(f ListFilter) (EventList, error) {
 }
 
 type CreateParams struct {
-	Name      string
-	GroupId   string
-	Capacity  int
-	Start     time.Time
-	Location  string
-	CreatorId string
+	Name        string
+	GroupId     string
+	Capacity    int
+	Start       time.Time
+	Location    string
+	VenueId     string
+	Description string
+	Cost        int
+	Tags        []string
+	Timezone    string // IANA timezone the event is scheduled in
+	CreatorId   string
 }
 
 func (s *service) Create(p CreateParams) (string, error) {
 	s.log.Printf("group Create params %+v", p)
+	p, err := validateCreate(p)
+	if err != nil {
+		return "", err
+	}
+
 	tx, err := s.db.Beginx()
 	if err != nil {
 		return "", err
@@ -133,43 +253,156 @@ func (s *service) Create(p CreateParams) (string, error) {
 	return id, nil
 }
 
+// Checks the fields every new event needs, whether it is created from scratch or duplicated, and normalizes the tags.
+func validateCreate(p CreateParams) (CreateParams, error) {
+	if len(p.Description) > MaxDescriptionLength {
+		return CreateParams{}, fmt.Errorf("description cannot be longer than %d characters", MaxDescriptionLength)
+	}
+	if p.Cost < 0 {
+		return CreateParams{}, ErrNegativeCost
+	}
+	if _, err := LoadLocation(p.Timezone); err != nil {
+		return CreateParams{}, err
+	}
+	tags, err := NormalizeTags(p.Tags)
+	if err != nil {
+		return CreateParams{}, err
+	}
+	p.Tags = tags
+
+	return p, nil
+}
+
 type UpdateParams struct {
-	Id       string
-	Name     string
-	Capacity int
-	Start    time.Time
-	Location string
+	Id          string
+	UserId      string // who is making the update, recorded in the revision
+	Name        string
+	Capacity    int
+	Start       time.Time
+	Location    string
+	VenueId     string
+	Description string
+	Cost        int
+	Tags        []string
+	Timezone    string
+	// Responses to protect from being moved to the waitlist, e.g. when lowering the capacity.
+	// Protection is kept for future waitlist changes.
+	ProtectedUserIds []string
 }
 
-func (s *service) Update(p UpdateParams) error {
+// Returns the responses that had their waitlist status changed by the update.
+func (s *service) Update(p UpdateParams) ([]EventResponse, error) {
 	s.log.Printf("group Update params %+v", p)
+	if len(p.Description) > MaxDescriptionLength {
+		return []EventResponse{}, fmt.Errorf("description cannot be longer than %d characters", MaxDescriptionLength)
+	}
+	if p.Cost < 0 {
+		return []EventResponse{}, ErrNegativeCost
+	}
+	if _, err := LoadLocation(p.Timezone); err != nil {
+		return []EventResponse{}, err
+	}
+	tags, err := NormalizeTags(p.Tags)
+	if err != nil {
+		return []EventResponse{}, err
+	}
+	p.Tags = tags
+
 	tx, err := s.db.Beginx()
 	if err != nil {
-		return err
+		return []EventResponse{}, err
+	}
+	defer tx.Rollback()
+
+	moved, err := applyUpdate(tx, p)
+	if err != nil {
+		return []EventResponse{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return []EventResponse{}, err
+	}
+
+	return moved, nil
+}
+
+// Dry run of Update, returns the responses that would have their waitlist status changed without saving anything.
+func (s *service) PreviewUpdate(p UpdateParams) ([]EventResponse, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []EventResponse{}, err
 	}
 	defer tx.Rollback()
 
+	return applyUpdate(tx, p)
+}
+
+func applyUpdate(tx *sqlx.Tx, p UpdateParams) ([]EventResponse, error) {
+	before, err := get(tx, p.Id)
+	if err != nil {
+		return []EventResponse{}, err
+	}
+	if before.IsCancelled() {
+		return []EventResponse{}, ErrCancelled
+	}
+
 	err = update(tx, p)
 	if err != nil {
-		return err
+		return []EventResponse{}, err
 	}
 
-	_, err = manageWaitlist(tx, p.Id)
+	err = setEventTags(tx, p.Id, p.Tags)
 	if err != nil {
-		return err
+		return []EventResponse{}, err
 	}
 
-	return tx.Commit()
+	err = protectResponses(tx, p.Id, p.ProtectedUserIds)
+	if err != nil {
+		return []EventResponse{}, err
+	}
+
+	moved, err := manageWaitlist(tx, p.Id)
+	if err != nil {
+		return []EventResponse{}, err
+	}
+
+	after, err := get(tx, p.Id)
+	if err != nil {
+		return []EventResponse{}, err
+	}
+
+	err = createRevision(tx, p.Id, p.UserId, diffEvents(before, after), moved)
+	if err != nil {
+		return []EventResponse{}, err
+	}
+
+	// manageWaitlist only returns the ids, fill in the rest for displaying
+	responses, err := listResponses(tx, p.Id)
+	if err != nil {
+		return []EventResponse{}, err
+	}
+	movedResponses := []EventResponse{}
+	for _, r := range responses {
+		for _, m := range moved {
+			if r.UserId == m.UserId {
+				movedResponses = append(movedResponses, r)
+			}
+		}
+	}
+
+	return movedResponses, nil
 }
 
-func (s *service) Delete(id string) error {
+// Soft deletes the event. It can be restored until it is purged.
+func (s *service) Delete(id string, userId string) error {
 	s.log.Printf("group Delete id %s", id)
 	stmt := `
         UPDATE event
-        SET is_deleted = TRUE
+        SET is_deleted = TRUE, deleted_at = ?, deleted_by = ?
         WHERE id = ?
     `
-	args := []any{id}
+	args := []any{time.Now().UTC(), userId, id}
 
 	_, err := s.db.Exec(stmt, args...)
 	if err != nil {
@@ -179,6 +412,206 @@ func (s *service) Delete(id string) error {
 	return nil
 }
 
+// Lists soft deleted events, most recently deleted first.
+func (s *service) ListDeleted() ([]Event, error) {
+	stmt := `
+        SELECT
+            e.id, e.name, e.start, e.created_at, e.creator_id, e.deleted_at
+            , u.full_name AS deleted_by_full_name
+        FROM event AS e
+        LEFT JOIN user AS u ON e.deleted_by = u.id
+        WHERE e.is_deleted = TRUE
+        ORDER BY e.deleted_at DESC
+    `
+
+	var events []Event
+	err := s.db.Select(&events, stmt)
+	return events, err
+}
+
+func (s *service) Restore(id string) error {
+	s.log.Printf("event Restore id %s", id)
+	stmt := `
+        UPDATE event
+        SET is_deleted = FALSE, deleted_at = NULL, deleted_by = NULL
+        WHERE id = ? AND is_deleted = TRUE
+    `
+	args := []any{id}
+
+	_, err := s.db.Exec(stmt, args...)
+	return err
+}
+
+// Hard deletes events that were soft deleted before the given time, along with their responses,
+// invitations and comments.
+//
+// Returns the number of purged events.
+func (s *service) Purge(before time.Time) (int, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var ids []string
+	stmt := `
+        SELECT id FROM event
+        WHERE is_deleted = TRUE AND datetime(deleted_at) < datetime(?)
+    `
+	err = tx.Select(&ids, stmt, before)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		err = purge(tx, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(ids) > 0 {
+		s.log.Printf("purged %d deleted event(s)", len(ids))
+	}
+	return len(ids), nil
+}
+
+type DuplicateParams struct {
+	Id        string
+	Start     time.Time
+	CreatorId string
+	// Invites everyone who responded to the original event, excluding the creator
+	InviteAttendees bool
+}
+
+// Creates a new event with the same fields as an existing one at a new start time.
+//
+// Returns the id of the new event.
+func (s *service) Duplicate(p DuplicateParams) (string, error) {
+	s.log.Printf("event Duplicate params %+v", p)
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	e, err := get(tx, p.Id)
+	if err != nil {
+		return "", err
+	}
+
+	cp, err := validateCreate(CreateParams{
+		Name:        e.Name,
+		GroupId:     e.GroupId.String,
+		Capacity:    e.Capacity,
+		Start:       p.Start,
+		Location:    e.Location,
+		VenueId:     e.VenueId.String,
+		Description: e.Description,
+		Cost:        e.Cost,
+		Tags:        e.Tags,
+		Timezone:    e.Timezone,
+		CreatorId:   p.CreatorId,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	id, err := create(tx, cp)
+	if err != nil {
+		return "", err
+	}
+
+	if p.InviteAttendees {
+		responses, err := listResponses(tx, p.Id)
+		if err != nil {
+			return "", err
+		}
+
+		for _, r := range responses {
+			if r.UserId == p.CreatorId {
+				continue
+			}
+			err = createInvitation(tx, id, r.UserId, p.CreatorId)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", err
+	}
+
+	s.log.Printf("duplicated event %s to %s", p.Id, id)
+	return id, nil
+}
+
+func (s *service) ListInvitations(eventId string) ([]EventInvitation, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []EventInvitation{}, err
+	}
+	defer tx.Rollback()
+
+	i, err := listInvitations(tx, eventId)
+	return i, err
+}
+
+type CancelParams struct {
+	Id     string
+	UserId string
+	Reason string
+}
+
+// Marks an event as cancelled with a reason. Existing responses are kept so responders can be notified.
+func (s *service) Cancel(p CancelParams) error {
+	s.log.Printf("event Cancel params %+v", p)
+	if strings.TrimSpace(p.Reason) == "" {
+		return errors.New("provide a reason for cancelling")
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	e, err := get(tx, p.Id)
+	if err != nil {
+		return err
+	}
+
+	if e.IsCancelled() {
+		return ErrCancelled
+	}
+
+	stmt := `
+        UPDATE event
+        SET cancelled_at = ?, cancelled_by = ?, cancel_reason = ?
+        WHERE id = ?
+    `
+	args := []any{
+		time.Now().UTC(),
+		p.UserId,
+		strings.TrimSpace(p.Reason),
+		p.Id,
+	}
+
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 type HandleResponseParams struct {
 	UserId        string
 	Id            string
@@ -211,6 +644,10 @@ func (s *service) HandleResponse(p HandleResponseParams) error {
 		return errors.New("cannot respond to past events")
 	}
 
+	if e.IsCancelled() {
+		return ErrCancelled
+	}
+
 	existingResponse, err := getUserResponse(tx, p.Id, p.UserId)
 	if err != nil {
 		return err
@@ -254,19 +691,22 @@ func (s *service) HandleResponse(p HandleResponseParams) error {
 func get(tx *sqlx.Tx, id string) (Event, error) {
 	stmt := `
         SELECT
-            e.id, e.name, e.capacity, e.start, e.location, e.created_at, e.creator_id
+            e.id, e.name, e.capacity, e.start, e.timezone, e.location, e.description, e.cost, e.created_at, e.creator_id
             , u.full_name AS creator_full_name
             , COALESCE((
                 SELECT SUM(attendee_count) FROM event_response
                 WHERE event_id = ? AND on_waitlist = FALSE
             ), 0) AS total_attendee_count
             , e.group_id, ug.name AS group_name
+            , e.venue_id, v.name AS venue_name, v.address AS venue_address, v.map_url AS venue_map_url, v.notes AS venue_notes
             , CASE
                 WHEN datetime() > datetime(start) THEN TRUE
                 ELSE FALSE
             END AS is_past
+            , e.cancelled_at, e.cancel_reason
         FROM event AS e
         LEFT JOIN user_group AS ug ON e.group_id = ug.id
+        LEFT JOIN venue AS v ON e.venue_id = v.id
         INNER JOIN user AS u ON e.creator_id = u.id
         WHERE e.id = ? AND e.is_deleted = FALSE 
     `
@@ -278,12 +718,17 @@ func get(tx *sqlx.Tx, id string) (Event, error) {
 		return Event{}, err
 	}
 
+	event.Tags, err = listEventTags(tx, event.Id)
+	if err != nil {
+		return Event{}, err
+	}
+
 	return event, nil
 }
 
 func listResponses(tx *sqlx.Tx, eventId string) ([]EventResponse, error) {
 	stmt := `
-        SELECT er.event_id, er.user_id, er.attendee_count, u.full_name AS user_full_name, er.created_at, er.on_waitlist
+        SELECT er.event_id, er.user_id, er.attendee_count, u.full_name AS user_full_name, er.created_at, er.on_waitlist, er.is_protected
         FROM event_response AS er
         INNER JOIN user AS u ON er.user_id = u.id
         WHERE er.event_id = ?
@@ -299,7 +744,7 @@ func listResponses(tx *sqlx.Tx, eventId string) ([]EventResponse, error) {
 	var responses []EventResponse
 	for rows.Next() {
 		var i EventResponse
-		if err := rows.Scan(&i.EventId, &i.UserId, &i.AttendeeCount, &i.UserFullName, &i.CreatedAt, &i.OnWaitlist); err != nil {
+		if err := rows.Scan(&i.EventId, &i.UserId, &i.AttendeeCount, &i.UserFullName, &i.CreatedAt, &i.OnWaitlist, &i.IsProtected); err != nil {
 			return []EventResponse{}, err
 		}
 		responses = append(responses, i)
@@ -333,13 +778,17 @@ func getUserResponse(tx *sqlx.Tx, eventId string, userId string) (*EventResponse
 func list(tx *sqlx.Tx, f ListFilter) (EventList, error) {
 	where, wargs := []string{}, []any{}
 
-	where = append(where, "is_deleted = FALSE")
+	where = append(where, "e.is_deleted = FALSE")
 	if f.Upcoming {
 		where = append(where, "datetime() <= datetime(start)")
 	}
 	if f.Past {
 		where = append(where, "datetime() > datetime(start)")
 	}
+	if f.Tag != "" {
+		where = append(where, "e.id IN (SELECT event_id FROM event_tag WHERE tag = ?)")
+		wargs = append(wargs, f.Tag)
+	}
 
 	// move the logic for determining if user can access event based off group from group service over to here
 	if f.UserId != "" {
@@ -354,10 +803,13 @@ func list(tx *sqlx.Tx, f ListFilter) (EventList, error) {
 
 	stmt := `
         SELECT 
-            e.id, e.name, e.capacity, e.start, e.location, e.created_at, e.creator_id
+            e.id, e.name, e.capacity, e.start, e.timezone, e.location, e.cost, e.created_at, e.creator_id
 		    , COALESCE (ec.total_attendee_count, 0) AS total_attendee_count
             , e.group_id
+            , e.venue_id, v.name AS venue_name
+            , e.cancelled_at, e.cancel_reason
         FROM event AS e
+        LEFT JOIN venue AS v ON e.venue_id = v.id
         LEFT JOIN (
             SELECT event_id, SUM(attendee_count) AS total_attendee_count FROM event_response
             WHERE on_waitlist = FALSE
@@ -377,6 +829,15 @@ func list(tx *sqlx.Tx, f ListFilter) (EventList, error) {
 		}, err
 	}
 
+	for i := range events {
+		events[i].Tags, err = listEventTags(tx, events[i].Id)
+		if err != nil {
+			return EventList{
+				Events: []Event{},
+			}, err
+		}
+	}
+
 	return EventList{
 		Events: events,
 	}, nil
@@ -389,8 +850,8 @@ func create(tx *sqlx.Tx, p CreateParams) (string, error) {
 	}
 
 	stmt := `
-        INSERT INTO event (id, name, group_id, capacity, start, location, created_at, creator_id)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+        INSERT INTO event (id, name, group_id, capacity, start, timezone, location, venue_id, description, cost, created_at, creator_id)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 	args := []any{
 		newId,
@@ -401,7 +862,14 @@ func create(tx *sqlx.Tx, p CreateParams) (string, error) {
 		},
 		p.Capacity,
 		p.Start,
+		p.Timezone,
 		p.Location,
+		sql.NullString{
+			String: p.VenueId,
+			Valid:  p.VenueId != "",
+		},
+		p.Description,
+		p.Cost,
 		time.Now().UTC(),
 		p.CreatorId,
 	}
@@ -411,20 +879,32 @@ func create(tx *sqlx.Tx, p CreateParams) (string, error) {
 		return "", err
 	}
 
+	err = setEventTags(tx, newId, p.Tags)
+	if err != nil {
+		return "", err
+	}
+
 	return newId, nil
 }
 
 func update(tx *sqlx.Tx, p UpdateParams) error {
 	stmt := `
 		        UPDATE event
-		        SET name = ?, capacity = ?, start = ?, location = ?
+		        SET name = ?, capacity = ?, start = ?, timezone = ?, location = ?, venue_id = ?, description = ?, cost = ?
 		        WHERE id = ?
 		    `
 	args := []any{
 		p.Name,
 		p.Capacity,
 		p.Start,
+		p.Timezone,
 		p.Location,
+		sql.NullString{
+			String: p.VenueId,
+			Valid:  p.VenueId != "",
+		},
+		p.Description,
+		p.Cost,
 		p.Id,
 	}
 
@@ -480,8 +960,102 @@ func updateResponse(tx *sqlx.Tx, p updateResponseParams) error {
 	return nil
 }
 
+func purge(tx *sqlx.Tx, id string) error {
+	stmts := []string{
+		`DELETE FROM event_response WHERE event_id = ?`,
+		`DELETE FROM event_invitation WHERE event_id = ?`,
+		`DELETE FROM event_payment WHERE event_id = ?`,
+		`DELETE FROM event_tag WHERE event_id = ?`,
+		`DELETE FROM event_match WHERE event_id = ?`,
+		`DELETE FROM event_team_member WHERE team_id IN (SELECT id FROM event_team WHERE event_id = ?)`,
+		`DELETE FROM event_team WHERE event_id = ?`,
+		`DELETE FROM event_comment_mention WHERE comment_id IN (SELECT id FROM event_comment WHERE event_id = ?)`,
+		`DELETE FROM event_comment WHERE event_id = ?`,
+		`DELETE FROM event_revision_change WHERE revision_id IN (SELECT id FROM event_revision WHERE event_id = ?)`,
+		`DELETE FROM event_revision_response WHERE revision_id IN (SELECT id FROM event_revision WHERE event_id = ?)`,
+		`DELETE FROM event_revision WHERE event_id = ?`,
+		`DELETE FROM event WHERE id = ?`,
+	}
+
+	for _, stmt := range stmts {
+		_, err := tx.Exec(stmt, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func createInvitation(tx *sqlx.Tx, eventId string, userId string, invitedBy string) error {
+	stmt := `
+        INSERT INTO event_invitation (event_id, user_id, invited_by, created_at)
+        VALUES (?, ?, ?, ?)
+        ON CONFLICT (event_id, user_id) DO NOTHING
+    `
+	args := []any{
+		eventId,
+		userId,
+		invitedBy,
+		time.Now().UTC(),
+	}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
+
+func listInvitations(tx *sqlx.Tx, eventId string) ([]EventInvitation, error) {
+	stmt := `
+        SELECT ei.event_id, ei.user_id, ei.invited_by, ei.created_at, u.full_name AS user_full_name
+        FROM event_invitation AS ei
+        INNER JOIN user AS u ON ei.user_id = u.id
+        WHERE ei.event_id = ?
+        ORDER BY u.full_name
+    `
+	args := []any{eventId}
+
+	var i []EventInvitation
+	err := tx.Select(&i, stmt, args...)
+	return i, err
+}
+
+func hasInvitation(tx *sqlx.Tx, eventId string, userId string) (bool, error) {
+	stmt := `
+        SELECT 1 FROM event_invitation
+        WHERE event_id = ? AND user_id = ?
+    `
+	args := []any{eventId, userId}
+
+	var i int
+	err := tx.Get(&i, stmt, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func protectResponses(tx *sqlx.Tx, eventId string, userIds []string) error {
+	for _, userId := range userIds {
+		stmt := `
+            UPDATE event_response
+            SET is_protected = TRUE
+            WHERE event_id = ? AND user_id = ?
+        `
+		args := []any{eventId, userId}
+		if _, err := tx.Exec(stmt, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Manages the waitlist status of all attendees in an event.
 // Based on the event's capacity, will convert all regular attendees to waitlist and all waitlist attendees to regular as necessary.
+// Protected responses get the first spots, the rest are first come first serve.
 //
 // Returns list of responses that had their waitlist status updated.
 func manageWaitlist(tx *sqlx.Tx, eventId string) ([]EventResponse, error) {
@@ -498,7 +1072,7 @@ func manageWaitlist(tx *sqlx.Tx, eventId string) ([]EventResponse, error) {
 					event_id
 					,user_id
 					,CASE
-						WHEN SUM(attendee_count) OVER (ORDER BY created_at) <= ? THEN FALSE
+						WHEN SUM(attendee_count) OVER (ORDER BY is_protected DESC, created_at) <= ? THEN FALSE
 						ELSE TRUE
 					END AS on_waitlist
 				FROM event_response
@@ -1,6 +1,8 @@
 package event_test
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 	"time"
 
@@ -368,7 +370,7 @@ func TestUpdate(t *testing.T) {
 			assert.Equal(t, false, responses[0].OnWaitlist)
 			assert.Equal(t, false, responses[1].OnWaitlist)
 
-			err = eventService.Update(event.UpdateParams{
+			_, err = eventService.Update(event.UpdateParams{
 				Id:       eventId,
 				Capacity: 1,
 			})
@@ -426,7 +428,7 @@ func TestUpdate(t *testing.T) {
 			assert.Equal(t, false, responses[0].OnWaitlist)
 			assert.Equal(t, false, responses[1].OnWaitlist)
 
-			err = eventService.Update(event.UpdateParams{
+			_, err = eventService.Update(event.UpdateParams{
 				Id:       eventId,
 				Capacity: 2,
 			})
@@ -484,7 +486,7 @@ func TestUpdate(t *testing.T) {
 			assert.Equal(t, false, responses[0].OnWaitlist)
 			assert.Equal(t, true, responses[1].OnWaitlist)
 
-			err = eventService.Update(event.UpdateParams{
+			_, err = eventService.Update(event.UpdateParams{
 				Id:       eventId,
 				Capacity: 2,
 			})
@@ -504,6 +506,276 @@ func TestUpdate(t *testing.T) {
 	})
 }
 
+func TestCancel(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := MustCreate(t, db, event.CreateParams{CreatorId: u1.Id, Start: time.Now().Add(day), Capacity: 4})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u1.Id, Id: id, AttendeeCount: 1})
+
+	err = eventService.Cancel(event.CancelParams{Id: id, UserId: u1.Id})
+	assert.Error(t, err)
+
+	err = eventService.Cancel(event.CancelParams{Id: id, UserId: u1.Id, Reason: "rain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cancelled events are still listed
+	events, err := eventService.List(event.ListFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(events.Events))
+	assert.True(t, events.Events[0].IsCancelled())
+	assert.Equal(t, "rain", events.Events[0].CancelReason)
+
+	err = eventService.HandleResponse(event.HandleResponseParams{UserId: u2.Id, Id: id, AttendeeCount: 1})
+	assert.ErrorIs(t, err, event.ErrCancelled)
+
+	err = eventService.Cancel(event.CancelParams{Id: id, UserId: u1.Id, Reason: "rain"})
+	assert.ErrorIs(t, err, event.ErrCancelled)
+
+	// cancelled events can no longer be edited, which would also move the waitlist
+	update := event.UpdateParams{Id: id, UserId: u1.Id, Start: time.Now().Add(day), Capacity: 1}
+	_, err = eventService.PreviewUpdate(update)
+	assert.ErrorIs(t, err, event.ErrCancelled)
+	_, err = eventService.Update(update)
+	assert.ErrorIs(t, err, event.ErrCancelled)
+
+	var buf bytes.Buffer
+	err = event.WriteICS(&buf, events.Events[0], "")
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "STATUS:CANCELLED\r\n")
+	assert.Contains(t, buf.String(), "DESCRIPTION:Cancelled: rain\r\n")
+}
+
+func TestRestore(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+
+	u, err := userService.Create(user.CreateParams{FullName: "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := MustCreate(t, db, event.CreateParams{CreatorId: u.Id, Start: time.Now().Add(day)})
+
+	err = eventService.Delete(id, u.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = eventService.Get(id)
+	assert.Error(t, err)
+
+	deleted, err := eventService.ListDeleted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(deleted))
+	assert.Equal(t, "name", deleted[0].DeletedByFullName.String)
+
+	err = eventService.Restore(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = eventService.Get(id)
+	assert.NoError(t, err)
+}
+
+func TestPurge(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+
+	u, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id1 := MustCreate(t, db, event.CreateParams{CreatorId: u.Id, Start: time.Now().Add(day), Capacity: 2})
+	id2 := MustCreate(t, db, event.CreateParams{CreatorId: u.Id, Start: time.Now().Add(day), Capacity: 2})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u.Id, Id: id1, AttendeeCount: 1})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u.Id, Id: id2, AttendeeCount: 1})
+
+	// lowering the capacity records a revision with a change and a moved response
+	u2, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u2.Id, Id: id1, AttendeeCount: 1})
+	_, err = eventService.Update(event.UpdateParams{Id: id1, UserId: u.Id, Start: time.Now().Add(day), Capacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = eventService.Delete(id1, u.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = eventService.Delete(id2, u.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only id1 has been deleted for longer than the grace period
+	_, err = db.Exec("UPDATE event SET deleted_at = ? WHERE id = ?", time.Now().Add(-31*day).UTC(), id1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := eventService.Purge(time.Now().Add(-30 * day))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, count)
+
+	deleted, err := eventService.ListDeleted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(deleted))
+	assert.Equal(t, id2, deleted[0].Id)
+
+	var responseCount int
+	err = db.Get(&responseCount, "SELECT COUNT(*) FROM event_response WHERE event_id = ?", id1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, responseCount)
+
+	for _, table := range []string{"event_revision", "event_revision_change", "event_revision_response"} {
+		var count int
+		err = db.Get(&count, "SELECT COUNT(*) FROM "+table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 0, count, table)
+	}
+}
+
+func TestDuplicate(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := MustCreate(t, db, event.CreateParams{
+		Name:      "name",
+		CreatorId: u1.Id,
+		Start:     time.Now().Add(day),
+		Capacity:  4,
+		Location:  "location",
+	})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u1.Id, Id: id, AttendeeCount: 1})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u2.Id, Id: id, AttendeeCount: 2})
+
+	start := time.Now().Add(7 * day).UTC().Truncate(time.Second)
+	newId, err := eventService.Duplicate(event.DuplicateParams{
+		Id:              id,
+		Start:           start,
+		CreatorId:       u1.Id,
+		InviteAttendees: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := eventService.Get(newId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "name", e.Name)
+	assert.Equal(t, 4, e.Capacity)
+	assert.Equal(t, "location", e.Location)
+	assert.True(t, start.Equal(e.Start))
+	assert.Equal(t, 0, e.TotalAttendeeCount)
+
+	// the creator is not invited to their own event
+	invitations, err := eventService.ListInvitations(newId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(invitations))
+	assert.Equal(t, u2.Id, invitations[0].UserId)
+
+	t.Run("validates like Create", func(t *testing.T) {
+		_, err := db.Exec("UPDATE event SET timezone = 'Not/AZone' WHERE id = ?", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = eventService.Duplicate(event.DuplicateParams{Id: id, Start: start, CreatorId: u1.Id})
+		assert.Error(t, err)
+	})
+}
+
+func TestCreateTemplate(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+
+	u, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := MustCreate(t, db, event.CreateParams{
+		Name:        "name",
+		CreatorId:   u.Id,
+		Capacity:    4,
+		Description: "description",
+		Cost:        500,
+		Tags:        []string{"indoor", "beginner"},
+		Timezone:    "America/New_York",
+	})
+
+	_, err = eventService.CreateTemplate(event.CreateTemplateParams{EventId: id, CreatorId: u.Id})
+	assert.Error(t, err)
+
+	templateId, err := eventService.CreateTemplate(event.CreateTemplateParams{EventId: id, TemplateName: "weekly", CreatorId: u.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	et, err := eventService.GetTemplate(templateId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := et.CreateParams()
+	assert.Equal(t, "name", p.Name)
+	assert.Equal(t, 4, p.Capacity)
+	assert.Equal(t, "description", p.Description)
+	assert.Equal(t, 500, p.Cost)
+	assert.Equal(t, []string{"beginner", "indoor"}, p.Tags)
+	assert.Equal(t, "America/New_York", p.Timezone)
+}
+
 func MustCreate(t testing.TB, db *db.DB, p event.CreateParams) string {
 	t.Helper()
 	id, err := event.NewService(db).Create(p)
@@ -520,3 +792,504 @@ func MustHandleResponse(t testing.TB, db *db.DB, p event.HandleResponseParams) {
 		t.Fatal(err)
 	}
 }
+
+func TestRevisions(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Add(day)
+	id := MustCreate(t, db, event.CreateParams{Name: "Run", CreatorId: u1.Id, Start: start, Location: "Park", Capacity: 2})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u1.Id, Id: id, AttendeeCount: 1})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u2.Id, Id: id, AttendeeCount: 1})
+
+	// no changes, no revision
+	_, err = eventService.Update(event.UpdateParams{Id: id, UserId: u1.Id, Name: "Run", Start: start, Location: "Park", Capacity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := eventService.GetDetailed(id, u1.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(e.Revisions))
+
+	_, err = eventService.Update(event.UpdateParams{Id: id, UserId: u1.Id, Name: "Long run", Start: start, Location: "Park", Capacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err = eventService.GetDetailed(id, u1.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 1, len(e.Revisions))
+	r := e.Revisions[0]
+	assert.Equal(t, u1.Id, r.UserId)
+	assert.Equal(t, []event.EventRevisionChange{
+		{RevisionId: r.Id, Field: "name", OldValue: "Run", NewValue: "Long run"},
+		{RevisionId: r.Id, Field: "capacity", OldValue: "2", NewValue: "1"},
+	}, r.Changes)
+	assert.Equal(t, 1, len(r.MovedResponses))
+	assert.Equal(t, u2.Id, r.MovedResponses[0].UserId)
+	assert.True(t, r.MovedResponses[0].OnWaitlist)
+}
+
+func TestPreviewUpdate(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u3, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Add(day)
+	id := MustCreate(t, db, event.CreateParams{CreatorId: u1.Id, Start: start, Location: "Park", Capacity: 3})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u1.Id, Id: id, AttendeeCount: 1})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u2.Id, Id: id, AttendeeCount: 1})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u3.Id, Id: id, AttendeeCount: 1})
+
+	p := event.UpdateParams{Id: id, UserId: u1.Id, Start: start, Location: "Park", Capacity: 2}
+	moved, err := eventService.PreviewUpdate(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(moved))
+	assert.Equal(t, u3.Id, moved[0].UserId)
+	assert.True(t, moved[0].OnWaitlist)
+
+	// nothing is saved
+	e, err := eventService.GetDetailed(id, u1.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, e.Capacity)
+	assert.Equal(t, 0, len(e.Revisions))
+	for _, r := range e.Responses {
+		assert.False(t, r.OnWaitlist)
+	}
+
+	// protecting the last responder bumps the one before instead
+	p.ProtectedUserIds = []string{u3.Id}
+	moved, err = eventService.Update(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(moved))
+	assert.Equal(t, u2.Id, moved[0].UserId)
+	assert.True(t, moved[0].OnWaitlist)
+
+	// protection is kept when the waitlist changes again
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u1.Id, Id: id, AttendeeCount: 0})
+	responses, err := eventService.ListResponses(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(responses))
+	for _, r := range responses {
+		assert.False(t, r.OnWaitlist)
+		assert.Equal(t, r.UserId == u3.Id, r.IsProtected)
+	}
+}
+
+func TestTags(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("normalize", func(t *testing.T) {
+		tags, err := event.NormalizeTags([]string{" Beach", "indoor", "beach ", ""})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"beach", "indoor"}, tags)
+
+		_, err = event.NormalizeTags([]string{"no spaces"})
+		assert.Error(t, err)
+
+		_, err = event.NormalizeTags([]string{"a", "b", "c", "d", "e", "f"})
+		assert.Error(t, err)
+	})
+
+	id1 := MustCreate(t, db, event.CreateParams{
+		Name:      "beach",
+		CreatorId: u1.Id,
+		Start:     time.Now().Add(day),
+		Location:  "location",
+		Tags:      []string{"Beach", "beginner"},
+	})
+	id2 := MustCreate(t, db, event.CreateParams{
+		Name:      "indoor",
+		CreatorId: u1.Id,
+		Start:     time.Now().Add(day),
+		Location:  "location",
+		Tags:      []string{"indoor"},
+	})
+
+	t.Run("create and filter", func(t *testing.T) {
+		e, err := eventService.Get(id1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"beach", "beginner"}, e.Tags)
+
+		el, err := eventService.List(event.ListFilter{Tag: "beach"})
+		assert.NoError(t, err)
+		assert.Len(t, el.Events, 1)
+		assert.Equal(t, id1, el.Events[0].Id)
+		assert.Equal(t, []string{"beach", "beginner"}, el.Events[0].Tags)
+
+		tags, err := eventService.ListTags()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"beach", "beginner", "indoor"}, tags)
+	})
+
+	t.Run("update replaces tags", func(t *testing.T) {
+		e, err := eventService.Get(id2)
+		assert.NoError(t, err)
+
+		_, err = eventService.Update(event.UpdateParams{
+			Id:       id2,
+			UserId:   u1.Id,
+			Name:     e.Name,
+			Capacity: e.Capacity,
+			Start:    e.Start,
+			Location: e.Location,
+			Tags:     []string{"beginner"},
+		})
+		assert.NoError(t, err)
+
+		e, err = eventService.Get(id2)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"beginner"}, e.Tags)
+
+		el, err := eventService.List(event.ListFilter{Tag: "beginner"})
+		assert.NoError(t, err)
+		assert.Len(t, el.Events, 2)
+
+		ed, err := eventService.GetDetailed(id2, u1.Id)
+		assert.NoError(t, err)
+		assert.Len(t, ed.Revisions, 1)
+		assert.Equal(t, "tags", ed.Revisions[0].Changes[0].Field)
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		assert.NoError(t, eventService.FollowTag(u1.Id, "Beach"))
+		assert.NoError(t, eventService.FollowTag(u1.Id, "beach"))
+		assert.NoError(t, eventService.FollowTag(u2.Id, "beginner"))
+		assert.Error(t, eventService.FollowTag(u2.Id, "not valid"))
+
+		tags, err := eventService.ListFollowedTags(u1.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"beach"}, tags)
+
+		followers, err := eventService.ListTagFollowers([]string{"beach", "beginner"})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{u1.Id, u2.Id}, followers)
+
+		assert.NoError(t, eventService.UnfollowTag(u1.Id, "beach"))
+		followers, err = eventService.ListTagFollowers([]string{"beach"})
+		assert.NoError(t, err)
+		assert.Empty(t, followers)
+	})
+}
+
+func TestListUserResponses(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	past := MustCreate(t, db, event.CreateParams{
+		Name:      "past",
+		CreatorId: u1.Id,
+		Start:     time.Now().Add(day),
+		Capacity:  4,
+		Location:  "location",
+	})
+	upcoming := MustCreate(t, db, event.CreateParams{
+		Name:      "upcoming",
+		CreatorId: u1.Id,
+		Start:     time.Now().Add(day),
+		Capacity:  4,
+		Location:  "location",
+	})
+	cancelled := MustCreate(t, db, event.CreateParams{
+		Name:      "cancelled",
+		CreatorId: u1.Id,
+		Start:     time.Now().Add(day),
+		Capacity:  4,
+		Location:  "location",
+	})
+	MustCreate(t, db, event.CreateParams{
+		Name:      "no response",
+		CreatorId: u1.Id,
+		Start:     time.Now().Add(day),
+		Capacity:  4,
+		Location:  "location",
+	})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u1.Id, Id: past, AttendeeCount: 2})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u1.Id, Id: upcoming, AttendeeCount: 1})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u1.Id, Id: cancelled, AttendeeCount: 1})
+	if err := eventService.Cancel(event.CancelParams{Id: cancelled, UserId: u1.Id, Reason: "rain"}); err != nil {
+		t.Fatal(err)
+	}
+	// can only respond to upcoming events, so move them into the past afterwards
+	for i, id := range []string{past, cancelled} {
+		_, err := db.Exec(`UPDATE event SET start = ? WHERE id = ?`, time.Now().Add(-time.Duration(i+1)*day).UTC(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	responses, err := eventService.ListUserResponses(u1.Id)
+	assert.NoError(t, err)
+	assert.Len(t, responses, 3)
+
+	assert.Equal(t, upcoming, responses[0].EventId)
+	assert.False(t, responses[0].IsPast)
+	assert.False(t, responses[0].Attended())
+
+	assert.Equal(t, past, responses[1].EventId)
+	assert.Equal(t, 1, responses[1].PlusOnes())
+	assert.True(t, responses[1].Attended())
+
+	assert.Equal(t, cancelled, responses[2].EventId)
+	assert.True(t, responses[2].IsCancelled())
+	assert.False(t, responses[2].Attended())
+}
+
+func TestWithdrawUpcomingResponses(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upcoming := MustCreate(t, db, event.CreateParams{
+		Name:      "upcoming",
+		CreatorId: u1.Id,
+		Start:     time.Now().Add(day),
+		Capacity:  1,
+		Location:  "location",
+	})
+	past := MustCreate(t, db, event.CreateParams{
+		Name:      "past",
+		CreatorId: u1.Id,
+		Start:     time.Now().Add(day),
+		Capacity:  1,
+		Location:  "location",
+	})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u1.Id, Id: upcoming, AttendeeCount: 1})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u2.Id, Id: upcoming, AttendeeCount: 1})
+	MustHandleResponse(t, db, event.HandleResponseParams{UserId: u1.Id, Id: past, AttendeeCount: 1})
+	_, err = db.Exec(`UPDATE event SET start = ? WHERE id = ?`, time.Now().Add(-day).UTC(), past)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withdrawn, err := eventService.WithdrawUpcomingResponses(u1.Id)
+	assert.NoError(t, err)
+	assert.Len(t, withdrawn, 1)
+	assert.Equal(t, upcoming, withdrawn[0].EventId)
+	assert.Len(t, withdrawn[0].Moved, 1)
+	assert.Equal(t, u2.Id, withdrawn[0].Moved[0].UserId)
+	assert.False(t, withdrawn[0].Moved[0].OnWaitlist)
+
+	responses, err := eventService.ListUserResponses(u1.Id)
+	assert.NoError(t, err)
+	assert.Len(t, responses, 1)
+	assert.Equal(t, past, responses[0].EventId)
+}
+
+func TestFormatCents(t *testing.T) {
+	assert.Equal(t, "0.00", event.FormatCents(0))
+	assert.Equal(t, "12.05", event.FormatCents(1205))
+	assert.Equal(t, "-0.50", event.FormatCents(-50))
+}
+
+func TestWriteICS(t *testing.T) {
+	e := event.Event{
+		Id:          "id",
+		Name:        "Pickup, indoors",
+		Start:       time.Date(2024, 6, 1, 18, 30, 0, 0, time.UTC),
+		Location:    "Gym",
+		Description: "Park in the back lot.\n\n" + strings.Repeat("Bring water; ", 10),
+	}
+
+	var buf bytes.Buffer
+	err := event.WriteICS(&buf, e, "https://example.com/event/id")
+	assert.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "UID:id@jvbe\r\n")
+	assert.Contains(t, out, "DTSTART:20240601T183000Z\r\n")
+	assert.Contains(t, out, "SUMMARY:Pickup\\, indoors\r\n")
+	assert.Contains(t, out, "URL:https://example.com/event/id\r\n")
+	for _, l := range strings.Split(out, "\r\n") {
+		assert.LessOrEqual(t, len(l), 75)
+	}
+	// unfolding the lines gives back the escaped description
+	assert.Contains(t, strings.ReplaceAll(out, "\r\n ", ""), "DESCRIPTION:Park in the back lot.\\n\\nBring water\\; ")
+}
+
+func TestParseLocalTime(t *testing.T) {
+	t.Run("DST", func(t *testing.T) {
+		// New York switches from EST (-5) to EDT (-4) on 2024-03-10
+		before, err := event.ParseLocalTime("2024-03-09T19:00", "America/New_York")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), before)
+
+		after, err := event.ParseLocalTime("2024-03-10T19:00", "America/New_York")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC), after)
+
+		// same wall clock time a day apart is only 23 hours apart
+		assert.Equal(t, 23*time.Hour, after.Sub(before))
+
+		// and back to EST on 2024-11-03
+		fall, err := event.ParseLocalTime("2024-11-03T19:00", "America/New_York")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 11, 4, 0, 0, 0, 0, time.UTC), fall)
+	})
+
+	t.Run("OtherCity", func(t *testing.T) {
+		// an organizer anywhere scheduling in Los Angeles gets the same time
+		start, err := event.ParseLocalTime("2024-07-01T18:30", "America/Los_Angeles")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 7, 2, 1, 30, 0, 0, time.UTC), start)
+		assert.Equal(t, "2024-07-01T18:30", event.FormatLocalTime(start, "America/Los_Angeles"))
+		assert.Equal(t, "2024-07-01T21:30", event.FormatLocalTime(start, "America/New_York"))
+	})
+
+	t.Run("FormatAcrossDST", func(t *testing.T) {
+		winter := time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC)
+		summer := time.Date(2024, 7, 15, 23, 0, 0, 0, time.UTC)
+		assert.Equal(t, "2024-01-15T18:00", event.FormatLocalTime(winter, "America/New_York"))
+		assert.Equal(t, "2024-07-15T19:00", event.FormatLocalTime(summer, "America/New_York"))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := event.ParseLocalTime("2024-03-09T19:00", "Mars/Olympus_Mons")
+		assert.ErrorIs(t, err, event.ErrInvalidTimezone)
+
+		_, err = event.ParseLocalTime("2024-03-09T19:00", "Local")
+		assert.ErrorIs(t, err, event.ErrInvalidTimezone)
+
+		_, err = event.ParseLocalTime("not a time", "UTC")
+		assert.Error(t, err)
+	})
+}
+
+func TestTimezone(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = eventService.Create(event.CreateParams{
+		CreatorId: u1.Id,
+		Start:     time.Now().Add(day),
+		Timezone:  "Nowhere/City",
+	})
+	assert.ErrorIs(t, err, event.ErrInvalidTimezone)
+
+	start, err := event.ParseLocalTime("2030-03-09T19:00", "America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := MustCreate(t, db, event.CreateParams{
+		Name:      "name",
+		CreatorId: u1.Id,
+		Start:     start,
+		Location:  "location",
+		Timezone:  "America/New_York",
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		e, err := eventService.Get(id)
+		assert.NoError(t, err)
+		assert.Equal(t, "America/New_York", e.Timezone)
+		assert.Equal(t, "2030-03-09T19:00", event.FormatLocalTime(e.Start, e.Timezone))
+	})
+
+	t.Run("Duplicate keeps timezone", func(t *testing.T) {
+		// a week later is across the DST change, the wall clock time stays the same
+		next, err := event.ParseLocalTime("2030-03-16T19:00", "America/New_York")
+		assert.NoError(t, err)
+
+		newId, err := eventService.Duplicate(event.DuplicateParams{Id: id, Start: next, CreatorId: u1.Id})
+		assert.NoError(t, err)
+
+		e, err := eventService.Get(newId)
+		assert.NoError(t, err)
+		assert.Equal(t, "America/New_York", e.Timezone)
+		assert.Equal(t, "2030-03-16T19:00", event.FormatLocalTime(e.Start, e.Timezone))
+		assert.Equal(t, 7*24*time.Hour-time.Hour, e.Start.Sub(start))
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		e, err := eventService.Get(id)
+		assert.NoError(t, err)
+
+		_, err = eventService.Update(event.UpdateParams{
+			Id:       id,
+			UserId:   u1.Id,
+			Name:     e.Name,
+			Start:    e.Start,
+			Location: e.Location,
+			Timezone: "America/Chicago",
+		})
+		assert.NoError(t, err)
+
+		e, err = eventService.Get(id)
+		assert.NoError(t, err)
+		assert.Equal(t, "America/Chicago", e.Timezone)
+		assert.Equal(t, "2030-03-09T18:00", event.FormatLocalTime(e.Start, e.Timezone))
+	})
+}
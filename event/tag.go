@@ -0,0 +1,180 @@
+package event
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	MaxTags      = 5
+	MaxTagLength = 20
+	tagRegex     = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+)
+
+// Lowercases, trims, and dedupes tags so "Beach" and "beach " are the same tag.
+// Returns an error if a tag has characters other than letters, numbers, and dashes.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		if len(t) > MaxTagLength || !tagRegex.MatchString(t) {
+			return []string{}, fmt.Errorf("invalid tag %q, tags can only have letters, numbers and dashes", t)
+		}
+		seen[t] = true
+		normalized = append(normalized, t)
+	}
+
+	if len(normalized) > MaxTags {
+		return []string{}, fmt.Errorf("maximum of %d tags allowed", MaxTags)
+	}
+
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// Lists every tag that is used by an event that is not deleted.
+func (s *service) ListTags() ([]string, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []string{}, err
+	}
+	defer tx.Rollback()
+
+	stmt := `
+        SELECT DISTINCT et.tag
+        FROM event_tag AS et
+        INNER JOIN event AS e ON et.event_id = e.id
+        WHERE e.is_deleted = FALSE
+        ORDER BY et.tag
+    `
+	var tags []string
+	err = tx.Select(&tags, stmt)
+	return tags, err
+}
+
+func (s *service) ListFollowedTags(userId string) ([]string, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []string{}, err
+	}
+	defer tx.Rollback()
+
+	stmt := `
+        SELECT tag FROM tag_follow
+        WHERE user_id = ?
+        ORDER BY tag
+    `
+	var tags []string
+	err = tx.Select(&tags, stmt, userId)
+	return tags, err
+}
+
+// Lists the users following any of the tags, each user only once.
+func (s *service) ListTagFollowers(tags []string) ([]string, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []string{}, err
+	}
+	defer tx.Rollback()
+
+	seen := map[string]bool{}
+	userIds := []string{}
+	for _, t := range tags {
+		stmt := `SELECT user_id FROM tag_follow WHERE tag = ?`
+
+		var ids []string
+		if err := tx.Select(&ids, stmt, t); err != nil {
+			return []string{}, err
+		}
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				userIds = append(userIds, id)
+			}
+		}
+	}
+
+	return userIds, nil
+}
+
+func (s *service) FollowTag(userId string, tag string) error {
+	tags, err := NormalizeTags([]string{tag})
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("tag cannot be empty")
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `
+        INSERT INTO tag_follow (user_id, tag, created_at)
+        VALUES (?, ?, ?)
+        ON CONFLICT (user_id, tag) DO NOTHING
+    `
+	args := []any{userId, tags[0], time.Now().UTC()}
+	if _, err := tx.Exec(stmt, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *service) UnfollowTag(userId string, tag string) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `DELETE FROM tag_follow WHERE user_id = ? AND tag = ?`
+	if _, err := tx.Exec(stmt, userId, tag); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func listEventTags(tx *sqlx.Tx, eventId string) ([]string, error) {
+	stmt := `
+        SELECT tag FROM event_tag
+        WHERE event_id = ?
+        ORDER BY tag
+    `
+	tags := []string{}
+	err := tx.Select(&tags, stmt, eventId)
+	return tags, err
+}
+
+// Replaces the tags of the event.
+func setEventTags(tx *sqlx.Tx, eventId string, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM event_tag WHERE event_id = ?`, eventId); err != nil {
+		return err
+	}
+
+	for _, t := range tags {
+		stmt := `
+            INSERT INTO event_tag (event_id, tag)
+            VALUES (?, ?)
+        `
+		if _, err := tx.Exec(stmt, eventId, t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
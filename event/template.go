@@ -0,0 +1,220 @@
+package event
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/mattfan00/jvbe/db"
+)
+
+// Saved set of event fields that new events can be started from.
+type EventTemplate struct {
+	Id           string         `db:"id"`
+	TemplateName string         `db:"template_name"`
+	Name         string         `db:"name"`
+	GroupId      sql.NullString `db:"group_id"`
+	Capacity     int            `db:"capacity"`
+	Location     string         `db:"location"`
+	VenueId      sql.NullString `db:"venue_id"`
+	Description  string         `db:"description"`
+	Cost         int            `db:"cost"`
+	Timezone     string         `db:"timezone"`
+	CreatedAt    time.Time      `db:"created_at"`
+	CreatorId    string         `db:"creator_id"`
+	Tags         []string
+}
+
+// Fills in CreateParams from the template. Start and CreatorId are left for the caller.
+func (t EventTemplate) CreateParams() CreateParams {
+	return CreateParams{
+		Name:        t.Name,
+		GroupId:     t.GroupId.String,
+		Capacity:    t.Capacity,
+		Location:    t.Location,
+		VenueId:     t.VenueId.String,
+		Description: t.Description,
+		Cost:        t.Cost,
+		Tags:        t.Tags,
+		Timezone:    t.Timezone,
+	}
+}
+
+func (s *service) GetTemplate(id string) (EventTemplate, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return EventTemplate{}, err
+	}
+	defer tx.Rollback()
+
+	t, err := getTemplate(tx, id)
+	return t, err
+}
+
+func (s *service) ListTemplates() ([]EventTemplate, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []EventTemplate{}, err
+	}
+	defer tx.Rollback()
+
+	t, err := listTemplates(tx)
+	return t, err
+}
+
+type CreateTemplateParams struct {
+	EventId      string
+	TemplateName string
+	CreatorId    string
+}
+
+// Saves the fields of an existing event as a template.
+func (s *service) CreateTemplate(p CreateTemplateParams) (string, error) {
+	s.log.Printf("event CreateTemplate params %+v", p)
+	if strings.TrimSpace(p.TemplateName) == "" {
+		return "", errors.New("template must have a name")
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	e, err := get(tx, p.EventId)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := createTemplate(tx, p, e)
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", err
+	}
+
+	s.log.Printf("created event template %s", id)
+	return id, nil
+}
+
+func (s *service) DeleteTemplate(id string) error {
+	s.log.Printf("event DeleteTemplate id %s", id)
+	stmt := `
+        UPDATE event_template
+        SET is_deleted = TRUE
+        WHERE id = ?
+    `
+	args := []any{id}
+
+	_, err := s.db.Exec(stmt, args...)
+	return err
+}
+
+func getTemplate(tx *sqlx.Tx, id string) (EventTemplate, error) {
+	stmt := `
+        SELECT
+            id, template_name, name, group_id, capacity, location, venue_id, description, cost, timezone,
+            created_at, creator_id
+        FROM event_template
+        WHERE id = ? AND is_deleted = FALSE
+    `
+	args := []any{id}
+
+	var t EventTemplate
+	err := tx.Get(&t, stmt, args...)
+	if err != nil {
+		return EventTemplate{}, err
+	}
+
+	t.Tags, err = listTemplateTags(tx, t.Id)
+	return t, err
+}
+
+func listTemplates(tx *sqlx.Tx) ([]EventTemplate, error) {
+	stmt := `
+        SELECT
+            id, template_name, name, group_id, capacity, location, venue_id, description, cost, timezone,
+            created_at, creator_id
+        FROM event_template
+        WHERE is_deleted = FALSE
+        ORDER BY template_name ASC
+    `
+
+	var t []EventTemplate
+	err := tx.Select(&t, stmt)
+	if err != nil {
+		return []EventTemplate{}, err
+	}
+
+	for i := range t {
+		t[i].Tags, err = listTemplateTags(tx, t[i].Id)
+		if err != nil {
+			return []EventTemplate{}, err
+		}
+	}
+
+	return t, nil
+}
+
+func createTemplate(tx *sqlx.Tx, p CreateTemplateParams, e Event) (string, error) {
+	id, err := gonanoid.New()
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `
+        INSERT INTO event_template (
+            id, template_name, name, group_id, capacity, location, venue_id, description, cost, timezone,
+            created_at, creator_id
+        )
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `
+	args := []any{
+		id,
+		strings.TrimSpace(p.TemplateName),
+		e.Name,
+		e.GroupId,
+		e.Capacity,
+		e.Location,
+		e.VenueId,
+		e.Description,
+		e.Cost,
+		e.Timezone,
+		db.Now(),
+		p.CreatorId,
+	}
+
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return "", err
+	}
+
+	for _, t := range e.Tags {
+		stmt := `
+            INSERT INTO event_template_tag (template_id, tag)
+            VALUES (?, ?)
+        `
+		if _, err := tx.Exec(stmt, id, t); err != nil {
+			return "", err
+		}
+	}
+
+	return id, nil
+}
+
+func listTemplateTags(tx *sqlx.Tx, templateId string) ([]string, error) {
+	stmt := `
+        SELECT tag FROM event_template_tag
+        WHERE template_id = ?
+        ORDER BY tag
+    `
+	tags := []string{}
+	err := tx.Select(&tags, stmt, templateId)
+	return tags, err
+}
@@ -0,0 +1,55 @@
+package event
+
+import (
+	"errors"
+	"time"
+	_ "time/tzdata" // the docker image has no zoneinfo
+)
+
+var (
+	ErrInvalidTimezone = errors.New("invalid timezone")
+)
+
+const localTimeLayout = "2006-01-02T15:04"
+
+// Loads an IANA timezone such as "America/New_York". An empty name is UTC.
+func LoadLocation(name string) (*time.Location, error) {
+	// "Local" would depend on the server's timezone
+	if name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+
+	return loc, nil
+}
+
+// Parses the wall clock time from a datetime-local input in the timezone.
+// The offset is the one in effect at that time rather than when the form is
+// submitted, so a time picked across a DST change keeps its wall clock time.
+func ParseLocalTime(value string, timezone string) (time.Time, error) {
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := time.ParseInLocation(localTimeLayout, value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.UTC(), nil
+}
+
+// Formats the time as the value of a datetime-local input in the timezone.
+func FormatLocalTime(t time.Time, timezone string) string {
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	return t.In(loc).Format(localTimeLayout)
+}
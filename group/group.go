@@ -12,9 +12,13 @@ type Service interface {
 	Get(string) (Group, error)
 	GetDetailed(string) (GroupDetailed, error)
 	List() ([]Group, error)
+	ListForUser(string) ([]Group, error)
 	CreateAndAddMember(CreateParams) (string, error)
 	Update(UpdateParams) error
-	Delete(string) error
+	Delete(string, string) error
+	ListDeleted() ([]Group, error)
+	Restore(string) error
+	Purge(time.Time) (int, error)
 	AddMemberFromInvite(string, string) (Group, error)
 	RemoveMember(string, string) error
 	UserCanAccess(sql.NullString, string) (bool, error)
@@ -24,14 +28,16 @@ type Service interface {
 }
 
 type Group struct {
-	Id               string    `db:"id"`
-	CreatedAt        time.Time `db:"created_at"`
-	CreatorId        string    `db:"creator_id"`
-	CreatorFullName  string    `db:"creator_full_name"`
-	IsDeleted        bool      `db:"is_deleted"`
-	Name             string    `db:"name"`
-	InviteId         string    `db:"invite_id"`
-	TotalMemberCount int       `db:"total_member_count"`
+	Id                string         `db:"id"`
+	CreatedAt         time.Time      `db:"created_at"`
+	CreatorId         string         `db:"creator_id"`
+	CreatorFullName   string         `db:"creator_full_name"`
+	IsDeleted         bool           `db:"is_deleted"`
+	Name              string         `db:"name"`
+	InviteId          string         `db:"invite_id"`
+	TotalMemberCount  int            `db:"total_member_count"`
+	DeletedAt         sql.NullTime   `db:"deleted_at"`
+	DeletedByFullName sql.NullString `db:"deleted_by_full_name"`
 }
 
 type GroupMember struct {
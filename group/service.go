@@ -74,6 +74,34 @@ func (s *service) List() ([]Group, error) {
 	return g, err
 }
 
+// Lists the groups the user is a member of.
+func (s *service) ListForUser(userId string) ([]Group, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Group{}, err
+	}
+	defer tx.Rollback()
+
+	stmt := `
+        SELECT
+            ug.id, ug.name
+            , COALESCE(ugc.total_member_count, 0) AS total_member_count
+        FROM user_group AS ug
+        INNER JOIN user_group_member AS ugm ON ug.id = ugm.group_id
+        LEFT JOIN (
+            SELECT group_id, COUNT(*) AS total_member_count FROM user_group_member
+            GROUP BY group_id
+        ) AS ugc ON ug.id = ugc.group_id
+        WHERE ugm.user_id = ? AND ug.is_deleted = FALSE
+        ORDER BY ug.name ASC
+    `
+	args := []any{userId}
+
+	g := []Group{}
+	err = tx.Select(&g, stmt, args...)
+	return g, err
+}
+
 type CreateParams struct {
 	CreatorId string
 	Name      string
@@ -125,7 +153,8 @@ func (s *service) Update(p UpdateParams) error {
 	return tx.Commit()
 }
 
-func (s *service) Delete(id string) error {
+// Soft deletes the group. It can be restored until it is purged.
+func (s *service) Delete(id string, userId string) error {
 	s.log.Printf("group Delete id %s", id)
 	tx, err := s.db.Beginx()
 	if err != nil {
@@ -133,7 +162,7 @@ func (s *service) Delete(id string) error {
 	}
 	defer tx.Rollback()
 
-	err = delete(tx, id)
+	err = delete(tx, id, userId)
 	if err != nil {
 		return err
 	}
@@ -141,6 +170,60 @@ func (s *service) Delete(id string) error {
 	return tx.Commit()
 }
 
+// Lists soft deleted groups, most recently deleted first.
+func (s *service) ListDeleted() ([]Group, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Group{}, err
+	}
+	defer tx.Rollback()
+
+	g, err := listDeleted(tx)
+	return g, err
+}
+
+func (s *service) Restore(id string) error {
+	s.log.Printf("group Restore id %s", id)
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = restore(tx, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Hard deletes groups that were soft deleted before the given time, along with their members.
+//
+// Returns the number of purged groups.
+func (s *service) Purge(before time.Time) (int, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	count, err := purge(tx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	if count > 0 {
+		s.log.Printf("purged %d deleted group(s)", count)
+	}
+	return count, nil
+}
+
 func (s *service) AddMemberFromInvite(inviteId string, userId string) (Group, error) {
 	s.log.Printf("group AddMemberFromInvite inviteId:%s userId:%s", inviteId, userId)
 	tx, err := s.db.Beginx()
@@ -361,11 +444,39 @@ func update(tx *sqlx.Tx, p UpdateParams) error {
 	return err
 }
 
-func delete(tx *sqlx.Tx, id string) error {
+func delete(tx *sqlx.Tx, id string, userId string) error {
 	stmt := `
         UPDATE user_group
-        SET is_deleted = TRUE
+        SET is_deleted = TRUE, deleted_at = ?, deleted_by = ?
         WHERE id = ?
+    `
+	args := []any{time.Now().UTC(), userId, id}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
+
+func listDeleted(tx *sqlx.Tx) ([]Group, error) {
+	stmt := `
+        SELECT
+            ug.id, ug.name, ug.created_at, ug.creator_id, ug.deleted_at
+            , u.full_name AS deleted_by_full_name
+        FROM user_group AS ug
+        LEFT JOIN user AS u ON ug.deleted_by = u.id
+        WHERE ug.is_deleted = TRUE
+        ORDER BY ug.deleted_at DESC
+    `
+
+	var g []Group
+	err := tx.Select(&g, stmt)
+	return g, err
+}
+
+func restore(tx *sqlx.Tx, id string) error {
+	stmt := `
+        UPDATE user_group
+        SET is_deleted = FALSE, deleted_at = NULL, deleted_by = NULL
+        WHERE id = ? AND is_deleted = TRUE
     `
 	args := []any{id}
 
@@ -373,6 +484,39 @@ func delete(tx *sqlx.Tx, id string) error {
 	return err
 }
 
+// Groups that still have events, polls or templates, including ones in the trash, are kept
+// until those are gone so that they are never left pointing at a group that no longer exists.
+func purge(tx *sqlx.Tx, before time.Time) (int, error) {
+	var ids []string
+	stmt := `
+        SELECT ug.id FROM user_group AS ug
+        WHERE ug.is_deleted = TRUE AND datetime(ug.deleted_at) < datetime(?)
+            AND NOT EXISTS (SELECT 1 FROM event WHERE group_id = ug.id)
+            AND NOT EXISTS (SELECT 1 FROM poll WHERE group_id = ug.id)
+            AND NOT EXISTS (SELECT 1 FROM event_template WHERE group_id = ug.id)
+    `
+	err := tx.Select(&ids, stmt, before)
+	if err != nil {
+		return 0, err
+	}
+
+	stmts := []string{
+		`DELETE FROM skill_rating WHERE group_id = ?`,
+		`DELETE FROM user_group_member WHERE group_id = ?`,
+		`DELETE FROM user_group WHERE id = ?`,
+	}
+	for _, id := range ids {
+		for _, stmt := range stmts {
+			_, err = tx.Exec(stmt, id)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return len(ids), nil
+}
+
 func removeMember(tx *sqlx.Tx, groupId string, userId string) error {
 	g, err := get(tx, groupId)
 	if g.CreatorId == userId {
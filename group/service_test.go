@@ -2,6 +2,7 @@ package group_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/mattfan00/jvbe/db"
 	"github.com/mattfan00/jvbe/event"
@@ -55,3 +56,104 @@ func TestFilterEventsUserCanAccess(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 0, len(u2Events))
 }
+
+func TestPurge(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+
+	groupService := group.NewService(db)
+	userService := user.NewService(db)
+
+	u, err := userService.Create(user.CreateParams{FullName: "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groupId, err := groupService.CreateAndAddMember(group.CreateParams{
+		CreatorId: u.Id,
+		Name:      "group",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = groupService.Delete(groupId, u.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := groupService.ListDeleted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(deleted))
+	assert.Equal(t, "name", deleted[0].DeletedByFullName.String)
+
+	// still within the grace period
+	count, err := groupService.Purge(time.Now().Add(-24 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	err = groupService.Restore(groupId)
+	assert.NoError(t, err)
+	_, err = groupService.Get(groupId)
+	assert.NoError(t, err)
+
+	err = groupService.Delete(groupId, u.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err = groupService.Purge(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var memberCount int
+	err = db.Get(&memberCount, "SELECT COUNT(*) FROM user_group_member WHERE group_id = ?", groupId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, memberCount)
+
+	t.Run("keeps groups that still have events", func(t *testing.T) {
+		eventService := event.NewService(db)
+
+		groupId, err := groupService.CreateAndAddMember(group.CreateParams{
+			CreatorId: u.Id,
+			Name:      "group with events",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		eventId, err := eventService.Create(event.CreateParams{
+			Name:      "event",
+			Start:     time.Now().Add(time.Hour),
+			Location:  "gym",
+			CreatorId: u.Id,
+			GroupId:   groupId,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = groupService.Delete(groupId, u.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count, err := groupService.Purge(time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+
+		err = eventService.Delete(eventId, u.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = eventService.Purge(time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		count, err = groupService.Purge(time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
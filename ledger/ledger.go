@@ -0,0 +1,112 @@
+package ledger
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/mattfan00/jvbe/event"
+)
+
+type Service interface {
+	ListEvent(string) ([]Entry, error)
+	ListGroupBalances(string) ([]Balance, error)
+	MarkPaid(MarkPaidParams) error
+	MarkUnpaid(string, string) error
+}
+
+// What a single user owes and has paid for an event.
+// Owed is computed from the event cost and the current attendees, so it follows responses and waitlist changes.
+type Entry struct {
+	EventId       string         `db:"event_id"`
+	EventName     string         `db:"event_name"`
+	EventStart    time.Time      `db:"event_start"`
+	EventCost     int            `db:"event_cost"`
+	GroupId       sql.NullString `db:"group_id"`
+	UserId        string         `db:"user_id"`
+	UserFullName  string         `db:"user_full_name"`
+	AttendeeCount int            `db:"attendee_count"`
+	Owed          int            // in cents
+	Paid          int            `db:"paid"` // in cents
+	PaidAt        sql.NullTime   `db:"paid_at"`
+}
+
+func (e Entry) IsPaid() bool {
+	return e.PaidAt.Valid
+}
+
+// Amount still owed, negative if the user paid more than their share, e.g. after dropping out.
+func (e Entry) Outstanding() int {
+	return e.Owed - e.Paid
+}
+
+// Totals for a user across all events of a group.
+type Balance struct {
+	UserId       string
+	UserFullName string
+	Owed         int
+	Paid         int
+}
+
+func (b Balance) Outstanding() int {
+	return b.Owed - b.Paid
+}
+
+// Amount paid beyond what is owed.
+func (b Balance) Credit() int {
+	return -b.Outstanding()
+}
+
+// Cost per attendee, rounded up to the cent so the cost is always covered.
+func Share(cost int, totalAttendeeCount int) int {
+	if totalAttendeeCount <= 0 {
+		return 0
+	}
+	return (cost + totalAttendeeCount - 1) / totalAttendeeCount
+}
+
+func WriteEntriesCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"event", "start", "name", "attendees", "owed", "paid", "outstanding", "paid_at"})
+	for _, e := range entries {
+		paidAt := ""
+		if e.PaidAt.Valid {
+			paidAt = e.PaidAt.Time.Format(time.RFC3339)
+		}
+		cw.Write([]string{
+			e.EventName,
+			e.EventStart.Format(time.RFC3339),
+			e.UserFullName,
+			strconv.Itoa(e.AttendeeCount),
+			event.FormatCents(e.Owed),
+			event.FormatCents(e.Paid),
+			event.FormatCents(e.Outstanding()),
+			paidAt,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func WriteBalancesCSV(w io.Writer, balances []Balance) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "owed", "paid", "outstanding"})
+	for _, b := range balances {
+		cw.Write([]string{
+			b.UserFullName,
+			event.FormatCents(b.Owed),
+			event.FormatCents(b.Paid),
+			event.FormatCents(b.Outstanding()),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var (
+	ErrNoCost      = errors.New("event does not have a cost")
+	ErrNotAttendee = errors.New("user is not attending the event")
+)
@@ -0,0 +1,208 @@
+package ledger
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/logger"
+)
+
+type service struct {
+	db  *db.DB
+	log logger.Logger
+}
+
+func NewService(db *db.DB) *service {
+	return &service{
+		db:  db,
+		log: logger.NewNoopLogger(),
+	}
+}
+
+func (s *service) SetLogger(l logger.Logger) {
+	s.log = l
+}
+
+// Lists what each attendee of the event owes and has paid. Deleted events have no entries, like in the group balances.
+func (s *service) ListEvent(eventId string) ([]Entry, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Entry{}, err
+	}
+	defer tx.Rollback()
+
+	e, err := listEntries(tx, "e.id = ? AND e.is_deleted = FALSE", eventId)
+	return e, err
+}
+
+// Sums up the entries of every event in the group that was not cancelled or deleted.
+func (s *service) ListGroupBalances(groupId string) ([]Balance, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Balance{}, err
+	}
+	defer tx.Rollback()
+
+	entries, err := listEntries(tx, "e.group_id = ? AND e.is_deleted = FALSE AND e.cancelled_at IS NULL", groupId)
+	if err != nil {
+		return []Balance{}, err
+	}
+
+	balances := []Balance{}
+	byUser := map[string]int{}
+	for _, e := range entries {
+		i, ok := byUser[e.UserId]
+		if !ok {
+			i = len(balances)
+			byUser[e.UserId] = i
+			balances = append(balances, Balance{
+				UserId:       e.UserId,
+				UserFullName: e.UserFullName,
+			})
+		}
+		balances[i].Owed += e.Owed
+		balances[i].Paid += e.Paid
+	}
+
+	sort.SliceStable(balances, func(i, j int) bool {
+		return balances[i].UserFullName < balances[j].UserFullName
+	})
+
+	return balances, nil
+}
+
+type MarkPaidParams struct {
+	EventId  string
+	UserId   string
+	MarkedBy string
+}
+
+// Records the user's current share as paid.
+func (s *service) MarkPaid(p MarkPaidParams) error {
+	s.log.Printf("ledger MarkPaid params %+v", p)
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	entries, err := listEntries(tx, "e.id = ?", p.EventId)
+	if err != nil {
+		return err
+	}
+
+	var entry *Entry
+	for i, e := range entries {
+		if e.UserId == p.UserId {
+			entry = &entries[i]
+		}
+	}
+	if entry == nil || entry.AttendeeCount == 0 {
+		return ErrNotAttendee
+	}
+	if entry.EventCost == 0 {
+		return ErrNoCost
+	}
+
+	err = upsertPayment(tx, p, entry.Owed)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *service) MarkUnpaid(eventId string, userId string) error {
+	s.log.Printf("ledger MarkUnpaid event %s user %s", eventId, userId)
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = deletePayment(tx, eventId, userId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Builds entries from the attendees and payments of events matching the where clause.
+// Users that paid but are no longer attending are included so their payment is not lost.
+func listEntries(tx *sqlx.Tx, where string, args ...any) ([]Entry, error) {
+	stmt := `
+        SELECT
+            e.id AS event_id, e.name AS event_name, e.start AS event_start, e.cost AS event_cost, e.group_id
+            , er.user_id, u.full_name AS user_full_name, er.attendee_count
+            , COALESCE(ep.amount, 0) AS paid, ep.paid_at
+        FROM event_response AS er
+        INNER JOIN event AS e ON er.event_id = e.id
+        INNER JOIN user AS u ON er.user_id = u.id
+        LEFT JOIN event_payment AS ep ON er.event_id = ep.event_id AND er.user_id = ep.user_id
+        WHERE er.on_waitlist = FALSE AND e.cost > 0 AND ` + where + `
+
+        UNION ALL
+
+        SELECT
+            e.id AS event_id, e.name AS event_name, e.start AS event_start, e.cost AS event_cost, e.group_id
+            , ep.user_id, u.full_name AS user_full_name, 0 AS attendee_count
+            , ep.amount AS paid, ep.paid_at
+        FROM event_payment AS ep
+        INNER JOIN event AS e ON ep.event_id = e.id
+        INNER JOIN user AS u ON ep.user_id = u.id
+        WHERE NOT EXISTS (
+            SELECT 1 FROM event_response AS er
+            WHERE er.event_id = ep.event_id AND er.user_id = ep.user_id AND er.on_waitlist = FALSE
+        ) AND ` + where + `
+
+        ORDER BY event_start, user_full_name
+    `
+	allArgs := append(append([]any{}, args...), args...)
+
+	var entries []Entry
+	err := tx.Select(&entries, stmt, allArgs...)
+	if err != nil {
+		return []Entry{}, err
+	}
+
+	totals := map[string]int{}
+	for _, e := range entries {
+		totals[e.EventId] += e.AttendeeCount
+	}
+	for i, e := range entries {
+		entries[i].Owed = Share(e.EventCost, totals[e.EventId]) * e.AttendeeCount
+	}
+
+	return entries, nil
+}
+
+func upsertPayment(tx *sqlx.Tx, p MarkPaidParams, amount int) error {
+	stmt := `
+        INSERT INTO event_payment (event_id, user_id, amount, paid_at, marked_by)
+        VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT (event_id, user_id) DO UPDATE SET
+            amount = excluded.amount,
+            paid_at = excluded.paid_at,
+            marked_by = excluded.marked_by
+    `
+	args := []any{p.EventId, p.UserId, amount, time.Now().UTC(), p.MarkedBy}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
+
+func deletePayment(tx *sqlx.Tx, eventId string, userId string) error {
+	stmt := `
+        DELETE FROM event_payment
+        WHERE event_id = ? AND user_id = ?
+    `
+	args := []any{eventId, userId}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
@@ -0,0 +1,187 @@
+package ledger_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/group"
+	"github.com/mattfan00/jvbe/ledger"
+	"github.com/mattfan00/jvbe/user"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var day = 24 * time.Hour
+
+func TestShare(t *testing.T) {
+	assert.Equal(t, 0, ledger.Share(1000, 0))
+	assert.Equal(t, 250, ledger.Share(1000, 4))
+	assert.Equal(t, 334, ledger.Share(1000, 3))
+}
+
+func TestListEvent(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+	ledgerService := ledger.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{FullName: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := userService.Create(user.CreateParams{FullName: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u3, err := userService.Create(user.CreateParams{FullName: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := eventService.Create(event.CreateParams{CreatorId: u1.Id, Start: time.Now().Add(day), Capacity: 3, Cost: 3000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []event.HandleResponseParams{
+		{UserId: u1.Id, Id: id, AttendeeCount: 1},
+		{UserId: u2.Id, Id: id, AttendeeCount: 2}, // plus one pays too
+		{UserId: u3.Id, Id: id, AttendeeCount: 1}, // waitlisted, doesn't owe anything
+	} {
+		if err := eventService.HandleResponse(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := ledgerService.ListEvent(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, 1000, entries[0].Owed)
+	assert.Equal(t, 2000, entries[1].Owed)
+
+	err = ledgerService.MarkPaid(ledger.MarkPaidParams{EventId: id, UserId: u3.Id, MarkedBy: u1.Id})
+	assert.ErrorIs(t, err, ledger.ErrNotAttendee)
+
+	err = ledgerService.MarkPaid(ledger.MarkPaidParams{EventId: id, UserId: u2.Id, MarkedBy: u1.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// u2 drops out after paying, their payment stays and the share goes up for everyone else
+	if err := eventService.HandleResponse(event.HandleResponseParams{UserId: u2.Id, Id: id, AttendeeCount: 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err = ledgerService.ListEvent(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, len(entries))
+	for _, e := range entries {
+		switch e.UserId {
+		case u1.Id, u3.Id:
+			assert.Equal(t, 1500, e.Owed)
+			assert.False(t, e.IsPaid())
+		case u2.Id:
+			assert.Equal(t, 0, e.Owed)
+			assert.Equal(t, -2000, e.Outstanding())
+			assert.True(t, e.IsPaid())
+		}
+	}
+
+	var buf bytes.Buffer
+	err = ledger.WriteEntriesCSV(&buf, entries)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(strings.Split(strings.TrimSpace(buf.String()), "\n")))
+	assert.Contains(t, buf.String(), ",b,0,0.00,20.00,-20.00,")
+
+	err = ledgerService.MarkUnpaid(id, u2.Id)
+	assert.NoError(t, err)
+	entries, err = ledgerService.ListEvent(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(entries))
+
+	err = eventService.Delete(id, u1.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err = ledgerService.ListEvent(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestListGroupBalances(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+	groupService := group.NewService(db)
+	ledgerService := ledger.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{FullName: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := userService.Create(user.CreateParams{FullName: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groupId, err := groupService.CreateAndAddMember(group.CreateParams{CreatorId: u1.Id, Name: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e1, err := eventService.Create(event.CreateParams{CreatorId: u1.Id, GroupId: groupId, Start: time.Now().Add(day), Capacity: 4, Cost: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2, err := eventService.Create(event.CreateParams{CreatorId: u1.Id, GroupId: groupId, Start: time.Now().Add(2 * day), Capacity: 4, Cost: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{e1, e2} {
+		for _, u := range []string{u1.Id, u2.Id} {
+			if err := eventService.HandleResponse(event.HandleResponseParams{UserId: u, Id: id, AttendeeCount: 1}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	err = ledgerService.MarkPaid(ledger.MarkPaidParams{EventId: e1, UserId: u2.Id, MarkedBy: u1.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balances, err := ledgerService.ListGroupBalances(groupId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []ledger.Balance{
+		{UserId: u1.Id, UserFullName: "a", Owed: 750, Paid: 0},
+		{UserId: u2.Id, UserFullName: "b", Owed: 750, Paid: 500},
+	}, balances)
+
+	// cancelled events are not charged
+	err = eventService.Cancel(event.CancelParams{Id: e2, UserId: u1.Id, Reason: "rain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	balances, err = ledgerService.ListGroupBalances(groupId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 500, balances[0].Owed)
+	assert.Equal(t, 0, balances[1].Outstanding())
+}
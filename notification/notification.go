@@ -0,0 +1,26 @@
+package notification
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Service interface {
+	Create(CreateParams) error
+	List(string) ([]Notification, error)
+	CountUnread(string) (int, error)
+	MarkAllRead(string) error
+}
+
+type Notification struct {
+	Id        string       `db:"id"`
+	UserId    string       `db:"user_id"`
+	CreatedAt time.Time    `db:"created_at"`
+	Message   string       `db:"message"`
+	Link      string       `db:"link"`
+	ReadAt    sql.NullTime `db:"read_at"`
+}
+
+func (n Notification) IsRead() bool {
+	return n.ReadAt.Valid
+}
@@ -0,0 +1,119 @@
+package notification
+
+import (
+	"github.com/jmoiron/sqlx"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/logger"
+)
+
+type service struct {
+	db  *db.DB
+	log logger.Logger
+}
+
+func NewService(db *db.DB) *service {
+	return &service{
+		db:  db,
+		log: logger.NewNoopLogger(),
+	}
+}
+
+func (s *service) SetLogger(l logger.Logger) {
+	s.log = l
+}
+
+type CreateParams struct {
+	UserIds []string
+	Message string
+	Link    string
+}
+
+// Creates the same notification for each of the given users.
+func (s *service) Create(p CreateParams) error {
+	if len(p.UserIds) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, userId := range p.UserIds {
+		err = create(tx, userId, p.Message, p.Link)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	s.log.Printf("notified %d user(s): %s", len(p.UserIds), p.Message)
+	return nil
+}
+
+func (s *service) List(userId string) ([]Notification, error) {
+	stmt := `
+        SELECT id, user_id, created_at, message, link, read_at
+        FROM notification
+        WHERE user_id = ?
+        ORDER BY created_at DESC
+        LIMIT 50
+    `
+	args := []any{userId}
+
+	var n []Notification
+	err := s.db.Select(&n, stmt, args...)
+	return n, err
+}
+
+func (s *service) CountUnread(userId string) (int, error) {
+	stmt := `
+        SELECT COUNT(*) FROM notification
+        WHERE user_id = ? AND read_at IS NULL
+    `
+	args := []any{userId}
+
+	var count int
+	err := s.db.Get(&count, stmt, args...)
+	return count, err
+}
+
+func (s *service) MarkAllRead(userId string) error {
+	stmt := `
+        UPDATE notification
+        SET read_at = ?
+        WHERE user_id = ? AND read_at IS NULL
+    `
+	args := []any{db.Now(), userId}
+
+	_, err := s.db.Exec(stmt, args...)
+	return err
+}
+
+func create(tx *sqlx.Tx, userId string, message string, link string) error {
+	id, err := gonanoid.New()
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+        INSERT INTO notification (id, user_id, created_at, message, link)
+        VALUES (?, ?, ?, ?, ?)
+    `
+	args := []any{
+		id,
+		userId,
+		db.Now(),
+		message,
+		link,
+	}
+
+	_, err = tx.Exec(stmt, args...)
+	return err
+}
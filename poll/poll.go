@@ -0,0 +1,131 @@
+package poll
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type Service interface {
+	Get(string, string) (PollDetailed, error)
+	List(ListFilter) ([]Poll, error)
+	Create(CreateParams) (string, error)
+	Vote(VoteParams) error
+	Close(CloseParams) error
+	SetEvent(string, string) error
+	Reopen(string) error
+}
+
+// A poll proposes candidate start times for an event before it is created.
+// Everything besides the start time is decided up front so the poll can be converted into an event.
+type Poll struct {
+	Id              string         `db:"id"`
+	Name            string         `db:"name"`
+	GroupId         sql.NullString `db:"group_id"`
+	GroupName       sql.NullString `db:"group_name"`
+	Capacity        int            `db:"capacity"`
+	Location        string         `db:"location"`
+	VenueId         sql.NullString `db:"venue_id"`
+	Description     string         `db:"description"`
+	Timezone        string         `db:"timezone"` // IANA timezone the options are picked in
+	CreatedAt       time.Time      `db:"created_at"`
+	CreatorId       string         `db:"creator_id"`
+	CreatorFullName string         `db:"creator_full_name"`
+	ClosedAt        sql.NullTime   `db:"closed_at"`
+	EventId         sql.NullString `db:"event_id"`  // event the poll was converted into
+	OptionId        sql.NullString `db:"option_id"` // option the event was created from
+	VoterCount      int            `db:"voter_count"`
+}
+
+func (p Poll) IsClosed() bool {
+	return p.ClosedAt.Valid
+}
+
+type Option struct {
+	Id         string    `db:"id"`
+	PollId     string    `db:"poll_id"`
+	Start      time.Time `db:"start"`
+	YesCount   int       `db:"yes_count"`
+	MaybeCount int       `db:"maybe_count"`
+	NoCount    int       `db:"no_count"`
+}
+
+// Maybe counts as half of a yes when picking the best option.
+func (o Option) Score() int {
+	return 2*o.YesCount + o.MaybeCount
+}
+
+type Answer string
+
+const (
+	AnswerYes   Answer = "yes"
+	AnswerMaybe Answer = "maybe"
+	AnswerNo    Answer = "no"
+)
+
+func (a Answer) IsValid() bool {
+	return a == AnswerYes || a == AnswerMaybe || a == AnswerNo
+}
+
+type Vote struct {
+	OptionId     string    `db:"option_id"`
+	UserId       string    `db:"user_id"`
+	UserFullName string    `db:"user_full_name"`
+	Answer       Answer    `db:"answer"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+type PollDetailed struct {
+	Poll
+	Options []Option
+	Votes   []Vote
+	// answers of the user viewing the poll, keyed by option id
+	UserAnswers map[string]Answer
+}
+
+// Ids of everyone that voted on at least one option.
+func (p PollDetailed) VoterIds() []string {
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, v := range p.Votes {
+		if !seen[v.UserId] {
+			seen[v.UserId] = true
+			ids = append(ids, v.UserId)
+		}
+	}
+	return ids
+}
+
+// Option with the highest score, ties go to the earliest start.
+// Returns nil if nobody has voted yes or maybe.
+func (p PollDetailed) BestOption() *Option {
+	var best *Option
+	for i, o := range p.Options {
+		if o.Score() == 0 {
+			continue
+		}
+		if best == nil || o.Score() > best.Score() {
+			best = &p.Options[i]
+		}
+	}
+	return best
+}
+
+// Votes for a single option, used for showing who answered what.
+func (p PollDetailed) OptionVotes(optionId string) []Vote {
+	votes := []Vote{}
+	for _, v := range p.Votes {
+		if v.OptionId == optionId {
+			votes = append(votes, v)
+		}
+	}
+	return votes
+}
+
+var MaxOptions = 10
+
+var (
+	ErrNoPoll   = errors.New("no poll found")
+	ErrNoOption = errors.New("option is not part of this poll")
+	ErrClosed   = errors.New("poll is closed")
+)
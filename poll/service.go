@@ -0,0 +1,438 @@
+package poll
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/logger"
+)
+
+type service struct {
+	db  *db.DB
+	log logger.Logger
+}
+
+func NewService(db *db.DB) *service {
+	return &service{
+		db:  db,
+		log: logger.NewNoopLogger(),
+	}
+}
+
+func (s *service) SetLogger(l logger.Logger) {
+	s.log = l
+}
+
+// Gets the poll with its options, votes, and the answers of the given user.
+func (s *service) Get(id string, userId string) (PollDetailed, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return PollDetailed{}, err
+	}
+	defer tx.Rollback()
+
+	p, err := get(tx, id)
+	if err != nil {
+		return PollDetailed{}, err
+	}
+
+	o, err := listOptions(tx, id)
+	if err != nil {
+		return PollDetailed{}, err
+	}
+
+	v, err := listVotes(tx, id)
+	if err != nil {
+		return PollDetailed{}, err
+	}
+
+	userAnswers := map[string]Answer{}
+	for _, vote := range v {
+		if vote.UserId == userId {
+			userAnswers[vote.OptionId] = vote.Answer
+		}
+	}
+
+	pd := PollDetailed{
+		Poll:        p,
+		Options:     o,
+		Votes:       v,
+		UserAnswers: userAnswers,
+	}
+
+	return pd, nil
+}
+
+type ListFilter struct {
+	UserId string // only polls the user can access
+	Open   bool
+}
+
+func (s *service) List(f ListFilter) ([]Poll, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Poll{}, err
+	}
+	defer tx.Rollback()
+
+	p, err := list(tx, f)
+	return p, err
+}
+
+type CreateParams struct {
+	Name        string
+	GroupId     string
+	Capacity    int
+	Location    string
+	VenueId     string
+	Description string
+	Timezone    string
+	CreatorId   string
+	Starts      []time.Time
+}
+
+func (s *service) Create(p CreateParams) (string, error) {
+	s.log.Printf("poll Create params %+v", p)
+	if strings.TrimSpace(p.Name) == "" {
+		return "", errors.New("poll must have a name")
+	}
+	if len(p.Starts) < 2 {
+		return "", errors.New("poll needs at least 2 options")
+	}
+	if len(p.Starts) > MaxOptions {
+		return "", fmt.Errorf("poll cannot have more than %d options", MaxOptions)
+	}
+	if _, err := event.LoadLocation(p.Timezone); err != nil {
+		return "", err
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	id, err := create(tx, p)
+	if err != nil {
+		return "", err
+	}
+
+	for _, start := range p.Starts {
+		err = createOption(tx, id, start)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", err
+	}
+
+	s.log.Printf("created poll %s", id)
+	return id, nil
+}
+
+type VoteParams struct {
+	Id      string
+	UserId  string
+	Answers map[string]Answer // keyed by option id
+}
+
+// Saves the user's answers, replacing any previous answer for the same option.
+func (s *service) Vote(p VoteParams) error {
+	s.log.Printf("poll Vote params %+v", p)
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	poll, err := get(tx, p.Id)
+	if err != nil {
+		return err
+	}
+
+	if poll.IsClosed() {
+		return ErrClosed
+	}
+
+	options, err := listOptions(tx, p.Id)
+	if err != nil {
+		return err
+	}
+
+	for optionId, answer := range p.Answers {
+		if !hasOption(options, optionId) {
+			return ErrNoOption
+		}
+		if !answer.IsValid() {
+			return fmt.Errorf("invalid answer %q", answer)
+		}
+
+		err = upsertVote(tx, optionId, p.UserId, answer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+type CloseParams struct {
+	Id       string
+	OptionId string
+}
+
+// Closes the poll before the option is converted into an event. Only one close can win,
+// so an option is never converted twice.
+func (s *service) Close(p CloseParams) error {
+	s.log.Printf("poll Close params %+v", p)
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	options, err := listOptions(tx, p.Id)
+	if err != nil {
+		return err
+	}
+
+	if !hasOption(options, p.OptionId) {
+		return ErrNoOption
+	}
+
+	err = closePoll(tx, p.Id, p.OptionId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Records the event the closed poll was converted into.
+func (s *service) SetEvent(id string, eventId string) error {
+	s.log.Printf("poll SetEvent id %s eventId %s", id, eventId)
+
+	stmt := `
+        UPDATE poll
+        SET event_id = ?
+        WHERE id = ? AND closed_at IS NOT NULL
+    `
+	_, err := s.db.Exec(stmt, eventId, id)
+	return err
+}
+
+// Opens the poll again when converting it into an event failed.
+func (s *service) Reopen(id string) error {
+	s.log.Printf("poll Reopen id %s", id)
+
+	stmt := `
+        UPDATE poll
+        SET closed_at = NULL, option_id = NULL
+        WHERE id = ? AND event_id IS NULL
+    `
+	_, err := s.db.Exec(stmt, id)
+	return err
+}
+
+func hasOption(options []Option, optionId string) bool {
+	for _, o := range options {
+		if o.Id == optionId {
+			return true
+		}
+	}
+	return false
+}
+
+func get(tx *sqlx.Tx, id string) (Poll, error) {
+	stmt := `
+        SELECT
+            p.id, p.name, p.group_id, p.capacity, p.location, p.venue_id, p.description, p.timezone,
+            p.created_at, p.creator_id, p.closed_at, p.event_id, p.option_id,
+            u.full_name AS creator_full_name, ug.name AS group_name
+        FROM poll AS p
+        INNER JOIN user AS u ON p.creator_id = u.id
+        LEFT JOIN user_group AS ug ON p.group_id = ug.id
+        WHERE p.id = ?
+    `
+	args := []any{id}
+
+	var p Poll
+	err := tx.Get(&p, stmt, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Poll{}, ErrNoPoll
+	} else if err != nil {
+		return Poll{}, err
+	}
+
+	return p, nil
+}
+
+func list(tx *sqlx.Tx, f ListFilter) ([]Poll, error) {
+	where := []string{"1 = 1"}
+	args := []any{}
+
+	if f.UserId != "" {
+		where = append(where, "(p.group_id IS NULL OR p.group_id IN (SELECT group_id FROM user_group_member WHERE user_id = ?))")
+		args = append(args, f.UserId)
+	}
+	if f.Open {
+		where = append(where, "p.closed_at IS NULL")
+	}
+
+	stmt := fmt.Sprintf(`
+        SELECT
+            p.id, p.name, p.group_id, p.capacity, p.location, p.venue_id, p.description, p.timezone,
+            p.created_at, p.creator_id, p.closed_at, p.event_id, p.option_id,
+            u.full_name AS creator_full_name, ug.name AS group_name,
+            (
+                SELECT COUNT(DISTINCT pv.user_id)
+                FROM poll_vote AS pv
+                INNER JOIN poll_option AS po ON pv.option_id = po.id
+                WHERE po.poll_id = p.id
+            ) AS voter_count
+        FROM poll AS p
+        INNER JOIN user AS u ON p.creator_id = u.id
+        LEFT JOIN user_group AS ug ON p.group_id = ug.id
+        WHERE %s
+        ORDER BY p.created_at DESC
+    `, strings.Join(where, " AND "))
+
+	var p []Poll
+	err := tx.Select(&p, stmt, args...)
+	return p, err
+}
+
+func listOptions(tx *sqlx.Tx, pollId string) ([]Option, error) {
+	stmt := `
+        SELECT
+            po.id, po.poll_id, po.start,
+            COUNT(CASE WHEN pv.answer = 'yes' THEN 1 END) AS yes_count,
+            COUNT(CASE WHEN pv.answer = 'maybe' THEN 1 END) AS maybe_count,
+            COUNT(CASE WHEN pv.answer = 'no' THEN 1 END) AS no_count
+        FROM poll_option AS po
+        LEFT JOIN poll_vote AS pv ON po.id = pv.option_id
+        WHERE po.poll_id = ?
+        GROUP BY po.id
+        ORDER BY po.start
+    `
+	args := []any{pollId}
+
+	var o []Option
+	err := tx.Select(&o, stmt, args...)
+	return o, err
+}
+
+func listVotes(tx *sqlx.Tx, pollId string) ([]Vote, error) {
+	stmt := `
+        SELECT pv.option_id, pv.user_id, pv.answer, pv.updated_at, u.full_name AS user_full_name
+        FROM poll_vote AS pv
+        INNER JOIN poll_option AS po ON pv.option_id = po.id
+        INNER JOIN user AS u ON pv.user_id = u.id
+        WHERE po.poll_id = ?
+        ORDER BY u.full_name
+    `
+	args := []any{pollId}
+
+	var v []Vote
+	err := tx.Select(&v, stmt, args...)
+	return v, err
+}
+
+func create(tx *sqlx.Tx, p CreateParams) (string, error) {
+	id, err := gonanoid.New()
+	if err != nil {
+		return "", err
+	}
+
+	groupId := sql.NullString{String: p.GroupId, Valid: p.GroupId != ""}
+	venueId := sql.NullString{String: p.VenueId, Valid: p.VenueId != ""}
+
+	stmt := `
+        INSERT INTO poll (id, name, group_id, capacity, location, venue_id, description, timezone, created_at, creator_id)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `
+	args := []any{
+		id,
+		strings.TrimSpace(p.Name),
+		groupId,
+		p.Capacity,
+		p.Location,
+		venueId,
+		p.Description,
+		p.Timezone,
+		db.Now(),
+		p.CreatorId,
+	}
+
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func createOption(tx *sqlx.Tx, pollId string, start time.Time) error {
+	id, err := gonanoid.New()
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+        INSERT INTO poll_option (id, poll_id, start)
+        VALUES (?, ?, ?)
+    `
+	args := []any{id, pollId, start.UTC()}
+
+	_, err = tx.Exec(stmt, args...)
+	return err
+}
+
+func upsertVote(tx *sqlx.Tx, optionId string, userId string, answer Answer) error {
+	stmt := `
+        INSERT INTO poll_vote (option_id, user_id, answer, updated_at)
+        VALUES (?, ?, ?, ?)
+        ON CONFLICT (option_id, user_id) DO UPDATE SET
+            answer = excluded.answer,
+            updated_at = excluded.updated_at
+    `
+	args := []any{optionId, userId, answer, db.Now()}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
+
+func closePoll(tx *sqlx.Tx, id string, optionId string) error {
+	stmt := `
+        UPDATE poll
+        SET closed_at = ?, option_id = ?
+        WHERE id = ? AND closed_at IS NULL
+    `
+	args := []any{db.Now(), optionId, id}
+
+	res, err := tx.Exec(stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrClosed
+	}
+	return nil
+}
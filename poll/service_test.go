@@ -0,0 +1,162 @@
+package poll_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/poll"
+	"github.com/mattfan00/jvbe/user"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var day = 24 * time.Hour
+
+func TestCreate(t *testing.T) {
+	t.Run("NotEnoughOptionsError", func(t *testing.T) {
+		_, err := poll.NewService(nil).Create(poll.CreateParams{Name: "run", Starts: []time.Time{time.Now()}})
+		assert.Error(t, err)
+	})
+
+	t.Run("NameRequiredError", func(t *testing.T) {
+		_, err := poll.NewService(nil).Create(poll.CreateParams{Starts: []time.Time{time.Now(), time.Now()}})
+		assert.Error(t, err)
+	})
+}
+
+func TestVote(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	pollService := poll.NewService(db)
+	userService := user.NewService(db)
+
+	u1, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	id, err := pollService.Create(poll.CreateParams{
+		Name:      "run",
+		CreatorId: u1.Id,
+		Starts:    []time.Time{now.Add(2 * day), now.Add(day)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := pollService.Get(id, u1.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(p.Options))
+	assert.True(t, p.Options[0].Start.Before(p.Options[1].Start))
+	assert.Nil(t, p.BestOption())
+	first, second := p.Options[0].Id, p.Options[1].Id
+
+	err = pollService.Vote(poll.VoteParams{Id: id, UserId: u1.Id, Answers: map[string]poll.Answer{first: poll.AnswerMaybe, second: poll.AnswerYes}})
+	assert.NoError(t, err)
+	err = pollService.Vote(poll.VoteParams{Id: id, UserId: u2.Id, Answers: map[string]poll.Answer{first: poll.AnswerYes, second: poll.AnswerNo}})
+	assert.NoError(t, err)
+
+	// changing an answer replaces it
+	err = pollService.Vote(poll.VoteParams{Id: id, UserId: u1.Id, Answers: map[string]poll.Answer{first: poll.AnswerYes}})
+	assert.NoError(t, err)
+
+	err = pollService.Vote(poll.VoteParams{Id: id, UserId: u1.Id, Answers: map[string]poll.Answer{"nope": poll.AnswerYes}})
+	assert.ErrorIs(t, err, poll.ErrNoOption)
+	err = pollService.Vote(poll.VoteParams{Id: id, UserId: u1.Id, Answers: map[string]poll.Answer{first: "sure"}})
+	assert.Error(t, err)
+
+	p, err = pollService.Get(id, u1.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, p.Options[0].YesCount)
+	assert.Equal(t, 1, p.Options[1].YesCount)
+	assert.Equal(t, 1, p.Options[1].NoCount)
+	assert.Equal(t, first, p.BestOption().Id)
+	assert.Equal(t, poll.AnswerYes, p.UserAnswers[first])
+	assert.ElementsMatch(t, []string{u1.Id, u2.Id}, p.VoterIds())
+
+	polls, err := pollService.List(poll.ListFilter{Open: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(polls))
+	assert.Equal(t, 2, polls[0].VoterCount)
+}
+
+func TestClose(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	pollService := poll.NewService(db)
+	userService := user.NewService(db)
+
+	u, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := pollService.Create(poll.CreateParams{
+		Name:      "run",
+		CreatorId: u.Id,
+		Starts:    []time.Time{time.Now().Add(day), time.Now().Add(2 * day)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := pollService.Get(id, u.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pollService.Close(poll.CloseParams{Id: id, OptionId: "nope"})
+	assert.ErrorIs(t, err, poll.ErrNoOption)
+
+	err = pollService.Close(poll.CloseParams{Id: id, OptionId: p.Options[0].Id})
+	assert.NoError(t, err)
+
+	t.Run("reopens when the event could not be created", func(t *testing.T) {
+		err := pollService.Reopen(id)
+		assert.NoError(t, err)
+		p, err := pollService.Get(id, u.Id)
+		assert.NoError(t, err)
+		assert.False(t, p.IsClosed())
+		assert.False(t, p.OptionId.Valid)
+
+		err = pollService.Close(poll.CloseParams{Id: id, OptionId: p.Options[0].Id})
+		assert.NoError(t, err)
+	})
+
+	err = pollService.SetEvent(id, "event")
+	assert.NoError(t, err)
+	err = pollService.Reopen(id)
+	assert.NoError(t, err)
+
+	p, err = pollService.Get(id, u.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, p.IsClosed())
+	assert.Equal(t, "event", p.EventId.String)
+	assert.Equal(t, p.Options[0].Id, p.OptionId.String)
+
+	err = pollService.Vote(poll.VoteParams{Id: id, UserId: u.Id, Answers: map[string]poll.Answer{p.Options[0].Id: poll.AnswerYes}})
+	assert.ErrorIs(t, err, poll.ErrClosed)
+	err = pollService.Close(poll.CloseParams{Id: id, OptionId: p.Options[0].Id})
+	assert.ErrorIs(t, err, poll.ErrClosed)
+
+	polls, err := pollService.List(poll.ListFilter{Open: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(polls))
+}
@@ -0,0 +1,332 @@
+package team
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+// Score of a game played between two of an event's teams.
+type Match struct {
+	Id           string    `db:"id"`
+	EventId      string    `db:"event_id"`
+	HomeTeamId   string    `db:"home_team_id"`
+	HomeTeamName string    `db:"home_team_name"`
+	AwayTeamId   string    `db:"away_team_id"`
+	AwayTeamName string    `db:"away_team_name"`
+	HomeScore    int       `db:"home_score"`
+	AwayScore    int       `db:"away_score"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// Results and attendance of a member across the past events of a group.
+type Stats struct {
+	UserId       string
+	UserFullName string
+	Attended     int
+	Wins         int
+	Losses       int
+	Draws        int
+}
+
+func (s Stats) Played() int {
+	return s.Wins + s.Losses + s.Draws
+}
+
+// Percentage of played matches that were won, draws count as half a win.
+func (s Stats) WinRate() int {
+	if s.Played() == 0 {
+		return 0
+	}
+	return (200*s.Wins + 100*s.Draws) / (2 * s.Played())
+}
+
+func (s *service) ListMatches(eventId string) ([]Match, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Match{}, err
+	}
+	defer tx.Rollback()
+
+	m, err := listMatches(tx, eventId)
+	return m, err
+}
+
+type CreateMatchParams struct {
+	EventId    string
+	HomeTeamId string
+	AwayTeamId string
+	HomeScore  int
+	AwayScore  int
+	CreatedBy  string
+}
+
+func (s *service) CreateMatch(p CreateMatchParams) (string, error) {
+	s.log.Printf("team CreateMatch params %+v", p)
+	if p.HomeTeamId == p.AwayTeamId {
+		return "", errors.New("a team cannot play itself")
+	}
+	if p.HomeScore < 0 || p.AwayScore < 0 {
+		return "", errors.New("score cannot be negative")
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	teams, err := listTeams(tx, p.EventId)
+	if err != nil {
+		return "", err
+	}
+	if !hasTeam(teams, p.HomeTeamId) || !hasTeam(teams, p.AwayTeamId) {
+		return "", ErrNoTeam
+	}
+
+	id, err := createMatch(tx, p)
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (s *service) DeleteMatch(eventId string, id string) error {
+	s.log.Printf("team DeleteMatch event %s id %s", eventId, id)
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = deleteMatch(tx, eventId, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Stats of every member of the group, ordered by wins and then win rate.
+// Only past events that were not cancelled or deleted are counted.
+func (s *service) ListGroupStats(groupId string) ([]Stats, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Stats{}, err
+	}
+	defer tx.Rollback()
+
+	stats, err := listGroupStats(tx, groupId)
+	if err != nil {
+		return []Stats{}, err
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Wins != stats[j].Wins {
+			return stats[i].Wins > stats[j].Wins
+		}
+		if stats[i].WinRate() != stats[j].WinRate() {
+			return stats[i].WinRate() > stats[j].WinRate()
+		}
+		return stats[i].Attended > stats[j].Attended
+	})
+
+	return stats, nil
+}
+
+func hasTeam(teams []Team, teamId string) bool {
+	for _, t := range teams {
+		if t.Id == teamId {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMatches(tx *sqlx.Tx, eventId string) (bool, error) {
+	stmt := `SELECT 1 FROM event_match WHERE event_id = ? LIMIT 1`
+
+	var i int
+	err := tx.Get(&i, stmt, eventId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func listMatches(tx *sqlx.Tx, eventId string) ([]Match, error) {
+	stmt := `
+        SELECT
+            m.id, m.event_id, m.home_team_id, m.away_team_id, m.home_score, m.away_score, m.created_at
+            , ht.name AS home_team_name, at.name AS away_team_name
+        FROM event_match AS m
+        INNER JOIN event_team AS ht ON m.home_team_id = ht.id
+        INNER JOIN event_team AS at ON m.away_team_id = at.id
+        WHERE m.event_id = ?
+        ORDER BY m.created_at
+    `
+	var m []Match
+	err := tx.Select(&m, stmt, eventId)
+	return m, err
+}
+
+func createMatch(tx *sqlx.Tx, p CreateMatchParams) (string, error) {
+	id, err := gonanoid.New()
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `
+        INSERT INTO event_match (id, event_id, home_team_id, away_team_id, home_score, away_score, created_at, created_by)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+    `
+	args := []any{
+		id,
+		p.EventId,
+		p.HomeTeamId,
+		p.AwayTeamId,
+		p.HomeScore,
+		p.AwayScore,
+		time.Now().UTC(),
+		p.CreatedBy,
+	}
+
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func deleteMatch(tx *sqlx.Tx, eventId string, id string) error {
+	stmt := `DELETE FROM event_match WHERE id = ? AND event_id = ?`
+
+	res, err := tx.Exec(stmt, id, eventId)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoMatch
+	}
+
+	return nil
+}
+
+func listGroupStats(tx *sqlx.Tx, groupId string) ([]Stats, error) {
+	stmt := `
+        SELECT ugm.user_id, u.full_name
+        FROM user_group_member AS ugm
+        INNER JOIN user AS u ON ugm.user_id = u.id
+        WHERE ugm.group_id = ?
+        ORDER BY u.full_name
+    `
+	rows, err := tx.Query(stmt, groupId)
+	if err != nil {
+		return []Stats{}, err
+	}
+	defer rows.Close()
+
+	stats := []Stats{}
+	byUser := map[string]int{}
+	for rows.Next() {
+		var s Stats
+		if err := rows.Scan(&s.UserId, &s.UserFullName); err != nil {
+			return []Stats{}, err
+		}
+		byUser[s.UserId] = len(stats)
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return []Stats{}, err
+	}
+
+	stmt = `
+        SELECT er.user_id, COUNT(*)
+        FROM event_response AS er
+        INNER JOIN event AS e ON er.event_id = e.id
+        WHERE e.group_id = ?
+            AND e.is_deleted = FALSE
+            AND e.cancelled_at IS NULL
+            AND datetime() > datetime(e.start)
+            AND er.on_waitlist = FALSE
+        GROUP BY er.user_id
+    `
+	rows, err = tx.Query(stmt, groupId)
+	if err != nil {
+		return []Stats{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userId string
+		var attended int
+		if err := rows.Scan(&userId, &attended); err != nil {
+			return []Stats{}, err
+		}
+		if i, ok := byUser[userId]; ok {
+			stats[i].Attended = attended
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return []Stats{}, err
+	}
+
+	// score of the member's team first, then the other team's
+	stmt = `
+        SELECT
+            etm.user_id
+            , CASE WHEN etm.team_id = m.home_team_id THEN m.home_score ELSE m.away_score END
+            , CASE WHEN etm.team_id = m.home_team_id THEN m.away_score ELSE m.home_score END
+        FROM event_match AS m
+        INNER JOIN event AS e ON m.event_id = e.id
+        INNER JOIN event_team_member AS etm ON etm.team_id IN (m.home_team_id, m.away_team_id)
+        WHERE e.group_id = ?
+            AND e.is_deleted = FALSE
+            AND e.cancelled_at IS NULL
+    `
+	rows, err = tx.Query(stmt, groupId)
+	if err != nil {
+		return []Stats{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userId string
+		var own, other int
+		if err := rows.Scan(&userId, &own, &other); err != nil {
+			return []Stats{}, err
+		}
+		i, ok := byUser[userId]
+		if !ok { // no longer a member
+			continue
+		}
+		switch {
+		case own > other:
+			stats[i].Wins++
+		case own < other:
+			stats[i].Losses++
+		default:
+			stats[i].Draws++
+		}
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,336 @@
+package team
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/logger"
+)
+
+type service struct {
+	db  *db.DB
+	log logger.Logger
+}
+
+func NewService(db *db.DB) *service {
+	return &service{
+		db:  db,
+		log: logger.NewNoopLogger(),
+	}
+}
+
+func (s *service) SetLogger(l logger.Logger) {
+	s.log = l
+}
+
+// Lists the rating of every member of the group, members without one get the default rating.
+func (s *service) ListRatings(groupId string) ([]Rating, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Rating{}, err
+	}
+	defer tx.Rollback()
+
+	r, err := listRatings(tx, groupId)
+	return r, err
+}
+
+type SetRatingsParams struct {
+	GroupId   string
+	Ratings   map[string]int // by user id
+	UpdatedBy string
+}
+
+// Sets the ratings of several members of the group at once, either all of them are saved or none are.
+func (s *service) SetRatings(p SetRatingsParams) error {
+	s.log.Printf("team SetRatings params %+v", p)
+	for _, r := range p.Ratings {
+		if r < MinRating || r > MaxRating {
+			return ErrInvalidRating
+		}
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	members, err := listRatings(tx, p.GroupId)
+	if err != nil {
+		return err
+	}
+	isMember := map[string]bool{}
+	for _, m := range members {
+		isMember[m.UserId] = true
+	}
+
+	for userId, r := range p.Ratings {
+		if !isMember[userId] {
+			return ErrNotMember
+		}
+
+		err = upsertRating(tx, p.GroupId, userId, r, p.UpdatedBy)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *service) ListTeams(eventId string) ([]Team, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Team{}, err
+	}
+	defer tx.Rollback()
+
+	t, err := listTeams(tx, eventId)
+	return t, err
+}
+
+type GenerateParams struct {
+	EventId   string
+	TeamCount int
+	CreatedBy string
+}
+
+// Splits the attendees that are not on the waitlist into balanced teams based on their rating in the event's group.
+// Replaces any teams that were generated before, as long as no match results were recorded for them.
+func (s *service) Generate(p GenerateParams) ([]Team, error) {
+	s.log.Printf("team Generate params %+v", p)
+	if p.TeamCount < 2 || p.TeamCount > MaxTeamCount {
+		return []Team{}, ErrInvalidTeamCount
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Team{}, err
+	}
+	defer tx.Rollback()
+
+	units, err := listUnits(tx, p.EventId)
+	if err != nil {
+		return []Team{}, err
+	}
+
+	if len(units) < p.TeamCount {
+		return []Team{}, ErrNotEnoughPlayers
+	}
+
+	matches, err := hasMatches(tx, p.EventId)
+	if err != nil {
+		return []Team{}, err
+	}
+	if matches {
+		return []Team{}, ErrHasMatches
+	}
+
+	err = deleteTeams(tx, p.EventId)
+	if err != nil {
+		return []Team{}, err
+	}
+
+	for i, members := range balance(units, p.TeamCount) {
+		teamId, err := createTeam(tx, p.EventId, fmt.Sprintf("Team %d", i+1), i, p.CreatedBy)
+		if err != nil {
+			return []Team{}, err
+		}
+
+		for _, m := range members {
+			err = createMember(tx, teamId, m)
+			if err != nil {
+				return []Team{}, err
+			}
+		}
+	}
+
+	teams, err := listTeams(tx, p.EventId)
+	if err != nil {
+		return []Team{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return []Team{}, err
+	}
+
+	return teams, nil
+}
+
+func (s *service) DeleteTeams(eventId string) error {
+	s.log.Printf("team DeleteTeams event %s", eventId)
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	matches, err := hasMatches(tx, eventId)
+	if err != nil {
+		return err
+	}
+	if matches {
+		return ErrHasMatches
+	}
+
+	err = deleteTeams(tx, eventId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func listRatings(tx *sqlx.Tx, groupId string) ([]Rating, error) {
+	stmt := `
+        SELECT
+            ugm.group_id, ugm.user_id, u.full_name AS user_full_name
+            , COALESCE(sr.rating, ?) AS rating
+            , sr.rating IS NOT NULL AS is_set
+        FROM user_group_member AS ugm
+        INNER JOIN user AS u ON ugm.user_id = u.id
+        LEFT JOIN skill_rating AS sr ON ugm.group_id = sr.group_id AND ugm.user_id = sr.user_id
+        WHERE ugm.group_id = ?
+        ORDER BY u.full_name
+    `
+	args := []any{DefaultRating, groupId}
+
+	var r []Rating
+	err := tx.Select(&r, stmt, args...)
+	return r, err
+}
+
+func upsertRating(tx *sqlx.Tx, groupId string, userId string, rating int, updatedBy string) error {
+	stmt := `
+        INSERT INTO skill_rating (group_id, user_id, rating, updated_at, updated_by)
+        VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT (group_id, user_id) DO UPDATE SET
+            rating = excluded.rating,
+            updated_at = excluded.updated_at,
+            updated_by = excluded.updated_by
+    `
+	args := []any{groupId, userId, rating, time.Now().UTC(), updatedBy}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
+
+// Attendees that are not on the waitlist along with their rating in the event's group.
+// Events without a group use the default rating for everyone.
+func listUnits(tx *sqlx.Tx, eventId string) ([]unit, error) {
+	stmt := `
+        SELECT er.user_id, er.attendee_count, COALESCE(sr.rating, ?) AS rating
+        FROM event_response AS er
+        INNER JOIN event AS e ON er.event_id = e.id
+        LEFT JOIN skill_rating AS sr ON e.group_id = sr.group_id AND er.user_id = sr.user_id
+        WHERE er.event_id = ? AND er.on_waitlist = FALSE AND e.is_deleted = FALSE
+        ORDER BY er.created_at
+    `
+	args := []any{DefaultRating, eventId}
+
+	rows, err := tx.Query(stmt, args...)
+	if err != nil {
+		return []unit{}, err
+	}
+	defer rows.Close()
+
+	units := []unit{}
+	for rows.Next() {
+		var u unit
+		if err := rows.Scan(&u.userId, &u.attendeeCount, &u.rating); err != nil {
+			return []unit{}, err
+		}
+		units = append(units, u)
+	}
+
+	return units, rows.Err()
+}
+
+func listTeams(tx *sqlx.Tx, eventId string) ([]Team, error) {
+	stmt := `
+        SELECT id, event_id, name, position, created_at
+        FROM event_team
+        WHERE event_id = ?
+        ORDER BY position
+    `
+	var teams []Team
+	if err := tx.Select(&teams, stmt, eventId); err != nil {
+		return []Team{}, err
+	}
+
+	stmt = `
+        SELECT etm.team_id, etm.user_id, etm.attendee_count, u.full_name AS user_full_name
+        FROM event_team_member AS etm
+        INNER JOIN event_team AS et ON etm.team_id = et.id
+        INNER JOIN user AS u ON etm.user_id = u.id
+        WHERE et.event_id = ?
+        ORDER BY u.full_name
+    `
+	var members []Member
+	if err := tx.Select(&members, stmt, eventId); err != nil {
+		return []Team{}, err
+	}
+
+	for i := range teams {
+		for _, m := range members {
+			if m.TeamId == teams[i].Id {
+				teams[i].Members = append(teams[i].Members, m)
+			}
+		}
+	}
+
+	return teams, nil
+}
+
+func createTeam(tx *sqlx.Tx, eventId string, name string, position int, createdBy string) (string, error) {
+	id, err := gonanoid.New()
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `
+        INSERT INTO event_team (id, event_id, name, position, created_at, created_by)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `
+	args := []any{id, eventId, name, position, time.Now().UTC(), createdBy}
+
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func createMember(tx *sqlx.Tx, teamId string, u unit) error {
+	stmt := `
+        INSERT INTO event_team_member (team_id, user_id, attendee_count)
+        VALUES (?, ?, ?)
+    `
+	args := []any{teamId, u.userId, u.attendeeCount}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
+
+func deleteTeams(tx *sqlx.Tx, eventId string) error {
+	stmts := []string{
+		`DELETE FROM event_team_member WHERE team_id IN (SELECT id FROM event_team WHERE event_id = ?)`,
+		`DELETE FROM event_team WHERE event_id = ?`,
+	}
+
+	for _, stmt := range stmts {
+		_, err := tx.Exec(stmt, eventId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
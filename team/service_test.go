@@ -0,0 +1,296 @@
+package team_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/group"
+	"github.com/mattfan00/jvbe/team"
+	"github.com/mattfan00/jvbe/user"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetRating(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	teamService := team.NewService(db)
+	groupService := group.NewService(db)
+	userService := user.NewService(db)
+
+	u, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	groupId, err := groupService.CreateAndAddMember(group.CreateParams{CreatorId: u.Id, Name: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ratings, err := teamService.ListRatings(groupId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(ratings))
+	assert.Equal(t, team.DefaultRating, ratings[0].Rating)
+	assert.False(t, ratings[0].IsSet)
+
+	outsider, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = teamService.SetRatings(team.SetRatingsParams{GroupId: groupId, Ratings: map[string]int{u.Id: 6}})
+	assert.ErrorIs(t, err, team.ErrInvalidRating)
+
+	// nothing is saved when one of the users is not a member
+	err = teamService.SetRatings(team.SetRatingsParams{GroupId: groupId, Ratings: map[string]int{u.Id: 5, outsider.Id: 4}, UpdatedBy: u.Id})
+	assert.ErrorIs(t, err, team.ErrNotMember)
+	ratings, err = teamService.ListRatings(groupId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, ratings[0].IsSet)
+
+	err = teamService.SetRatings(team.SetRatingsParams{GroupId: groupId, Ratings: map[string]int{u.Id: 5}, UpdatedBy: u.Id})
+	assert.NoError(t, err)
+
+	ratings, err = teamService.ListRatings(groupId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, ratings[0].Rating)
+	assert.True(t, ratings[0].IsSet)
+}
+
+func TestGenerate(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	teamService := team.NewService(db)
+	groupService := group.NewService(db)
+	userService := user.NewService(db)
+	eventService := event.NewService(db)
+
+	creator, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	groupId, err := groupService.CreateAndAddMember(group.CreateParams{CreatorId: creator.Id, Name: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventId, err := eventService.Create(event.CreateParams{CreatorId: creator.Id, GroupId: groupId, Start: time.Now().Add(24 * time.Hour), Capacity: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the last responder ends up on the waitlist
+	ratings := []int{3, 5, 4, 3, 2, 1, 5}
+	attendeeCounts := []int{2, 1, 1, 1, 1, 1, 1}
+	ratingByUser := map[string]int{}
+	pairUserId := ""
+	for i, r := range ratings {
+		u, err := userService.Create(user.CreateParams{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attendeeCounts[i] == 2 {
+			pairUserId = u.Id
+		}
+		ratingByUser[u.Id] = r
+		_, err = db.Exec(`INSERT INTO user_group_member (group_id, user_id, created_at) VALUES (?, ?, ?)`, groupId, u.Id, time.Now().UTC())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = teamService.SetRatings(team.SetRatingsParams{GroupId: groupId, Ratings: map[string]int{u.Id: r}, UpdatedBy: creator.Id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = eventService.HandleResponse(event.HandleResponseParams{UserId: u.Id, Id: eventId, AttendeeCount: attendeeCounts[i]})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = teamService.Generate(team.GenerateParams{EventId: eventId, TeamCount: 1})
+	assert.ErrorIs(t, err, team.ErrInvalidTeamCount)
+	_, err = teamService.Generate(team.GenerateParams{EventId: eventId, TeamCount: 8})
+	assert.ErrorIs(t, err, team.ErrNotEnoughPlayers)
+
+	teams, err := teamService.Generate(team.GenerateParams{EventId: eventId, TeamCount: 2, CreatedBy: creator.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(teams))
+
+	players := []int{}
+	strengths := []int{}
+	for _, tm := range teams {
+		strength := 0
+		for _, m := range tm.Members {
+			strength += ratingByUser[m.UserId] * m.AttendeeCount
+			// plus ones stay with the user who brought them
+			if m.UserId == pairUserId {
+				assert.Equal(t, 2, m.AttendeeCount)
+			}
+		}
+		players = append(players, tm.PlayerCount())
+		strengths = append(strengths, strength)
+	}
+	// 7 players with a total strength of 21
+	assert.ElementsMatch(t, []int{4, 3}, players)
+	assert.LessOrEqual(t, abs(strengths[0]-strengths[1]), 1)
+
+	// regenerating replaces the previous teams
+	_, err = teamService.Generate(team.GenerateParams{EventId: eventId, TeamCount: 3, CreatedBy: creator.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	teams, err = teamService.ListTeams(eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, len(teams))
+
+	err = teamService.DeleteTeams(eventId)
+	assert.NoError(t, err)
+	teams, err = teamService.ListTeams(eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(teams))
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+func TestMatches(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	teamService := team.NewService(db)
+	groupService := group.NewService(db)
+	userService := user.NewService(db)
+	eventService := event.NewService(db)
+
+	creator, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	groupId, err := groupService.CreateAndAddMember(group.CreateParams{CreatorId: creator.Id, Name: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := groupService.Get(groupId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Add(24 * time.Hour)
+	eventId, err := eventService.Create(event.CreateParams{CreatorId: creator.Id, GroupId: groupId, Start: start, Capacity: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userIds := []string{creator.Id}
+	for i := 0; i < 3; i++ {
+		u, err := userService.Create(user.CreateParams{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := groupService.AddMemberFromInvite(g.InviteId, u.Id); err != nil {
+			t.Fatal(err)
+		}
+		userIds = append(userIds, u.Id)
+	}
+	for _, userId := range userIds {
+		err = eventService.HandleResponse(event.HandleResponseParams{UserId: userId, Id: eventId, AttendeeCount: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	teams, err := teamService.Generate(team.GenerateParams{EventId: eventId, TeamCount: 2, CreatedBy: creator.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = teamService.CreateMatch(team.CreateMatchParams{EventId: eventId, HomeTeamId: teams[0].Id, AwayTeamId: teams[0].Id})
+	assert.Error(t, err)
+	_, err = teamService.CreateMatch(team.CreateMatchParams{EventId: eventId, HomeTeamId: teams[0].Id, AwayTeamId: "nope"})
+	assert.ErrorIs(t, err, team.ErrNoTeam)
+
+	matchId, err := teamService.CreateMatch(team.CreateMatchParams{EventId: eventId, HomeTeamId: teams[0].Id, AwayTeamId: teams[1].Id, HomeScore: 25, AwayScore: 20, CreatedBy: creator.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = teamService.CreateMatch(team.CreateMatchParams{EventId: eventId, HomeTeamId: teams[1].Id, AwayTeamId: teams[0].Id, HomeScore: 15, AwayScore: 15, CreatedBy: creator.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := teamService.ListMatches(eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(matches))
+	assert.Equal(t, "Team 1", matches[0].HomeTeamName)
+
+	// teams are locked once results are recorded
+	_, err = teamService.Generate(team.GenerateParams{EventId: eventId, TeamCount: 2, CreatedBy: creator.Id})
+	assert.ErrorIs(t, err, team.ErrHasMatches)
+	err = teamService.DeleteTeams(eventId)
+	assert.ErrorIs(t, err, team.ErrHasMatches)
+
+	// attendance only counts once the event is over
+	_, err = eventService.Update(event.UpdateParams{Id: eventId, UserId: creator.Id, Start: time.Now().Add(-time.Hour), Capacity: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := teamService.ListGroupStats(groupId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 4, len(stats))
+	winners := map[string]bool{}
+	for _, m := range teams[0].Members {
+		winners[m.UserId] = true
+	}
+	for i, s := range stats {
+		assert.Equal(t, 1, s.Attended)
+		assert.Equal(t, 2, s.Played())
+		assert.Equal(t, 1, s.Draws)
+		if winners[s.UserId] {
+			assert.Equal(t, 1, s.Wins)
+			assert.Equal(t, 75, s.WinRate())
+			assert.Less(t, i, 2) // winners are ranked first
+		} else {
+			assert.Equal(t, 1, s.Losses)
+			assert.Equal(t, 25, s.WinRate())
+		}
+	}
+
+	// a match can only be deleted through its own event
+	err = teamService.DeleteMatch("other", matchId)
+	assert.ErrorIs(t, err, team.ErrNoMatch)
+	matches, err = teamService.ListMatches(eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(matches))
+
+	err = teamService.DeleteMatch(eventId, matchId)
+	assert.NoError(t, err)
+	matches, err = teamService.ListMatches(eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(matches))
+}
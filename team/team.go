@@ -0,0 +1,134 @@
+package team
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+type Service interface {
+	ListRatings(string) ([]Rating, error)
+	SetRatings(SetRatingsParams) error
+	ListTeams(string) ([]Team, error)
+	Generate(GenerateParams) ([]Team, error)
+	DeleteTeams(string) error
+	ListMatches(string) ([]Match, error)
+	CreateMatch(CreateMatchParams) (string, error)
+	DeleteMatch(string, string) error
+	ListGroupStats(string) ([]Stats, error)
+}
+
+// Skill rating of a group member, only visible to organizers.
+type Rating struct {
+	GroupId      string `db:"group_id"`
+	UserId       string `db:"user_id"`
+	UserFullName string `db:"user_full_name"`
+	Rating       int    `db:"rating"`
+	IsSet        bool   `db:"is_set"` // false if the member has not been rated and has the default rating
+}
+
+var (
+	MinRating     = 1
+	MaxRating     = 5
+	DefaultRating = 3
+	MaxTeamCount  = 8
+)
+
+type Team struct {
+	Id        string    `db:"id"`
+	EventId   string    `db:"event_id"`
+	Name      string    `db:"name"`
+	Position  int       `db:"position"`
+	CreatedAt time.Time `db:"created_at"`
+	Members   []Member
+}
+
+func (t Team) PlayerCount() int {
+	c := 0
+	for _, m := range t.Members {
+		c += m.AttendeeCount
+	}
+	return c
+}
+
+type Member struct {
+	TeamId        string `db:"team_id"`
+	UserId        string `db:"user_id"`
+	UserFullName  string `db:"user_full_name"`
+	AttendeeCount int    `db:"attendee_count"`
+}
+
+func (m Member) PlusOnes() int {
+	return m.AttendeeCount - 1
+}
+
+// A user and their plus ones, which always end up on the same team.
+type unit struct {
+	userId        string
+	attendeeCount int
+	rating        int // plus ones count with the rating of the user who brought them
+}
+
+func (u unit) strength() int {
+	return u.rating * u.attendeeCount
+}
+
+// Splits units into n teams of at most ceil(players / n) players each, keeping the total strength even.
+// Stronger units are placed first, each on the weakest team that still has room, so weaker ones can even out the difference.
+//
+// Returns the units of each team.
+func balance(units []unit, n int) [][]unit {
+	sorted := append([]unit{}, units...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].strength() != sorted[j].strength() {
+			return sorted[i].strength() > sorted[j].strength()
+		}
+		return sorted[i].attendeeCount > sorted[j].attendeeCount
+	})
+
+	total := 0
+	for _, u := range units {
+		total += u.attendeeCount
+	}
+	maxPlayers := (total + n - 1) / n
+
+	teams := make([][]unit, n)
+	players := make([]int, n)
+	strength := make([]int, n)
+	for _, u := range sorted {
+		best := -1
+		for i := 0; i < n; i++ {
+			if players[i]+u.attendeeCount > maxPlayers {
+				continue
+			}
+			if best == -1 || strength[i] < strength[best] {
+				best = i
+			}
+		}
+		// plus ones can make a unit not fit anywhere, fall back to the team with the fewest players
+		if best == -1 {
+			best = 0
+			for i := 1; i < n; i++ {
+				if players[i] < players[best] {
+					best = i
+				}
+			}
+		}
+
+		teams[best] = append(teams[best], u)
+		players[best] += u.attendeeCount
+		strength[best] += u.strength()
+	}
+
+	return teams
+}
+
+var (
+	ErrInvalidRating    = errors.New("rating must be between 1 and 5")
+	ErrInvalidTeamCount = errors.New("invalid number of teams")
+	ErrNotEnoughPlayers = errors.New("not enough attendees for that many teams")
+	ErrNoTeam           = errors.New("team is not part of this event")
+	ErrNotMember        = errors.New("user is not a member of this group")
+	ErrNoMatch          = errors.New("no match found")
+	ErrHasMatches       = errors.New("teams cannot be changed after match results are recorded")
+)
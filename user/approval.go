@@ -0,0 +1,91 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Rules that approve new users without waiting for a reviewer.
+type AutoApproveRules struct {
+	InviteGroupIds []string // signed up through the invite link of one of these groups
+	EmailDomains   []string // verified email from the identity provider is in one of these domains
+	Vouch          bool     // signed up through the referral link of an active member
+}
+
+// Where a new user came from, used to evaluate the auto-approval rules.
+type Signup struct {
+	InviteId      string // group invite link the user followed before logging in
+	ReferralToken string // token of the member referral link the user followed
+	Rules         AutoApproveRules
+}
+
+// Returns a description of the first rule the user matches, or an empty string if none match.
+func matchApprovalRule(tx *sqlx.Tx, eu ExternalUser, s Signup) (string, error) {
+	if s.InviteId != "" && len(s.Rules.InviteGroupIds) > 0 {
+		var g struct {
+			Id   string `db:"id"`
+			Name string `db:"name"`
+		}
+		stmt := `
+            SELECT id, name FROM user_group
+            WHERE invite_id = ? AND is_deleted = FALSE
+        `
+		err := tx.Get(&g, stmt, s.InviteId)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+		if err == nil && slices.Contains(s.Rules.InviteGroupIds, g.Id) {
+			return fmt.Sprintf("invite link of %s", g.Name), nil
+		}
+	}
+
+	if eu.EmailVerified {
+		if _, domain, ok := strings.Cut(strings.ToLower(eu.Email), "@"); ok {
+			for _, d := range s.Rules.EmailDomains {
+				if strings.ToLower(d) == domain {
+					return fmt.Sprintf("email domain %s", domain), nil
+				}
+			}
+		}
+	}
+
+	if s.ReferralToken != "" && s.Rules.Vouch {
+		voucher, err := getReferrer(tx, s.ReferralToken)
+		if err != nil && !errors.Is(err, ErrNoUser) {
+			return "", err
+		}
+		if err == nil {
+			return fmt.Sprintf("vouched for by %s", voucher.FullName), nil
+		}
+	}
+
+	return "", nil
+}
+
+func autoApprove(tx *sqlx.Tx, userId string, rule string) error {
+	stmt := `
+        INSERT INTO user_review (user_id, created_at, reviewed_at, is_approved, approval_rule)
+        VALUES (?, ?, ?, 1, ?)
+        ON CONFLICT (user_id) DO UPDATE SET
+            reviewed_at = excluded.reviewed_at
+            , is_approved = excluded.is_approved
+            , reviewer_id = NULL
+            , reviewer_comment = NULL
+            , approval_rule = excluded.approval_rule
+    `
+	now := time.Now().UTC()
+	args := []any{userId, now, now, rule}
+
+	_, err := tx.Exec(stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	return setStatus(tx, userId, UserStatusActive)
+}
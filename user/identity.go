@@ -0,0 +1,155 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrIdentityInUse = errors.New("this login belongs to another user, ask an admin to merge the accounts")
+	ErrLastIdentity  = errors.New("you cannot unlink your only login")
+)
+
+// An external subject, from an IdP, the user can log in as.
+type Identity struct {
+	Issuer    string    `db:"issuer"`
+	Subject   string    `db:"subject"`
+	UserId    string    `db:"user_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (s *service) ListIdentities(userId string) ([]Identity, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Identity{}, err
+	}
+	defer tx.Rollback()
+
+	return listIdentities(tx, userId)
+}
+
+// Links another external identity to the user so that they can log in with either.
+func (s *service) LinkIdentity(userId string, eu ExternalUser) error {
+	s.log.Printf("user LinkIdentity user %s issuer %s subject %s", userId, eu.Issuer, eu.Id)
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = claimLegacyIdentity(tx, eu, s.legacyIssuer)
+	if err != nil {
+		return err
+	}
+
+	u, err := getByExternal(tx, eu.Issuer, eu.Id)
+	if err == nil {
+		if u.Id != userId {
+			return ErrIdentityInUse
+		}
+		// already linked
+		return nil
+	} else if !errors.Is(err, ErrNoUser) {
+		return err
+	}
+
+	err = createIdentity(tx, userId, eu.Issuer, eu.Id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *service) UnlinkIdentity(userId string, issuer string, subject string) error {
+	s.log.Printf("user UnlinkIdentity user %s issuer %s subject %s", userId, issuer, subject)
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	identities, err := listIdentities(tx, userId)
+	if err != nil {
+		return err
+	}
+	if len(identities) <= 1 {
+		return ErrLastIdentity
+	}
+
+	stmt := `
+        DELETE FROM user_identity
+        WHERE issuer = ? AND subject = ? AND user_id = ?
+    `
+	_, err = tx.Exec(stmt, issuer, subject, userId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func getByExternal(tx *sqlx.Tx, issuer string, subject string) (User, error) {
+	stmt := `
+        SELECT ` + userColumns + `
+        FROM user
+        WHERE id = (
+            SELECT user_id FROM user_identity
+            WHERE issuer = ? AND subject = ?
+        )
+    `
+	args := []any{issuer, subject}
+
+	var user User
+	err := tx.Get(&user, stmt, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNoUser
+	} else if err != nil {
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+// Identities from before issuers were recorded have an empty issuer, the first login with the subject
+// from the legacy issuer fills it in.
+func claimLegacyIdentity(tx *sqlx.Tx, eu ExternalUser, legacyIssuer string) error {
+	if eu.Issuer == "" || eu.Issuer != legacyIssuer {
+		return nil
+	}
+
+	stmt := `
+        UPDATE OR IGNORE user_identity
+        SET issuer = ?
+        WHERE issuer = '' AND subject = ?
+    `
+	_, err := tx.Exec(stmt, eu.Issuer, eu.Id)
+	return err
+}
+
+func listIdentities(tx *sqlx.Tx, userId string) ([]Identity, error) {
+	stmt := `
+        SELECT issuer, subject, user_id, created_at
+        FROM user_identity
+        WHERE user_id = ?
+        ORDER BY created_at
+    `
+
+	identities := []Identity{}
+	err := tx.Select(&identities, stmt, userId)
+	return identities, err
+}
+
+func createIdentity(tx *sqlx.Tx, userId string, issuer string, subject string) error {
+	stmt := `
+        INSERT INTO user_identity (issuer, subject, user_id, created_at)
+        VALUES (?, ?, ?, ?)
+    `
+	args := []any{issuer, subject, userId, time.Now().UTC()}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
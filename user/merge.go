@@ -0,0 +1,268 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattfan00/jvbe/event"
+)
+
+type MergeParams struct {
+	FromId string // duplicate account, left behind pointing at ToId
+	ToId   string // account that is kept
+}
+
+type MergeResult struct {
+	From User
+	To   User
+	// events both users responded to, only one of the responses is kept so the waitlist may need to move up
+	ConflictEventIds []string
+	// responses moved on or off the waitlist, by conflicting event id
+	Moved map[string][]event.EventResponse
+}
+
+// How far along a user is in the review, the merged user keeps the review of the one further along.
+// A suspension always wins so that it cannot be dodged with a second account.
+var reviewRank = map[UserStatus]int{
+	UserStatusInactive:  0,
+	UserStatusRejected:  1,
+	UserStatusActive:    2,
+	UserStatusSuspended: 3,
+}
+
+// Columns that only point at a user, moving them can never conflict.
+var mergeReferences = []struct{ table, column string }{
+	{"event", "creator_id"},
+	{"event", "cancelled_by"},
+	{"event", "deleted_by"},
+	{"event_comment", "user_id"},
+	{"event_comment", "deleted_by"},
+	{"event_invitation", "invited_by"},
+	{"event_match", "created_by"},
+	{"event_payment", "marked_by"},
+	{"event_revision", "user_id"},
+	{"event_team", "created_by"},
+	{"event_template", "creator_id"},
+	{"notification", "user_id"},
+	{"poll", "creator_id"},
+	{"skill_rating", "updated_by"},
+	{"user_group", "creator_id"},
+	{"user_group", "deleted_by"},
+	{"user_identity", "user_id"},
+	{"user_review", "reviewer_id"},
+	{"venue", "creator_id"},
+}
+
+// Tables with the user in their primary key. When both users have a row for the same key, the row of the kept user wins.
+var mergeKeyedReferences = []struct{ table, column string }{
+	{"event_comment_mention", "user_id"},
+	{"event_invitation", "user_id"},
+	{"event_revision_response", "user_id"},
+	{"event_team_member", "user_id"},
+	{"poll_vote", "user_id"},
+	{"skill_rating", "user_id"},
+	{"tag_follow", "user_id"},
+	{"user_referral", "user_id"},
+	{"user_group_member", "user_id"},
+	{"user_vouch", "user_id"},
+	{"user_vouch", "voucher_id"},
+}
+
+// Moves everything of a duplicate account to the user that is kept in a single transaction.
+// The duplicate's identities move too, so logging in with them continues as the kept user.
+func (s *service) Merge(p MergeParams) (MergeResult, error) {
+	s.log.Printf("user Merge params %+v", p)
+	if p.FromId == p.ToId {
+		return MergeResult{}, errors.New("cannot merge a user into themselves")
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return MergeResult{}, err
+	}
+	defer tx.Rollback()
+
+	from, err := get(tx, p.FromId)
+	if err != nil {
+		return MergeResult{}, err
+	}
+	to, err := get(tx, p.ToId)
+	if err != nil {
+		return MergeResult{}, err
+	}
+	if _, ok := reviewRank[from.Status]; !ok {
+		return MergeResult{}, fmt.Errorf("%s: %w", from.FullName, ErrInvalidStatus)
+	}
+	if _, ok := reviewRank[to.Status]; !ok {
+		return MergeResult{}, fmt.Errorf("%s: %w", to.FullName, ErrInvalidStatus)
+	}
+
+	conflicts, err := mergeResponses(tx, from.Id, to.Id)
+	if err != nil {
+		return MergeResult{}, err
+	}
+	moved := map[string][]event.EventResponse{}
+	for _, eventId := range conflicts {
+		moved[eventId], err = event.UpdateWaitlistTx(tx, eventId)
+		if err != nil {
+			return MergeResult{}, err
+		}
+	}
+
+	err = mergePayments(tx, from.Id, to.Id)
+	if err != nil {
+		return MergeResult{}, err
+	}
+
+	for _, r := range mergeReferences {
+		stmt := fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ?`, r.table, r.column, r.column)
+		_, err = tx.Exec(stmt, to.Id, from.Id)
+		if err != nil {
+			return MergeResult{}, err
+		}
+	}
+
+	for _, r := range mergeKeyedReferences {
+		stmt := fmt.Sprintf(`UPDATE OR IGNORE %s SET %s = ? WHERE %s = ?`, r.table, r.column, r.column)
+		_, err = tx.Exec(stmt, to.Id, from.Id)
+		if err != nil {
+			return MergeResult{}, err
+		}
+
+		stmt = fmt.Sprintf(`DELETE FROM %s WHERE %s = ?`, r.table, r.column)
+		_, err = tx.Exec(stmt, from.Id)
+		if err != nil {
+			return MergeResult{}, err
+		}
+	}
+	// the two users may have vouched for each other
+	_, err = tx.Exec(`DELETE FROM user_vouch WHERE user_id = voucher_id`)
+	if err != nil {
+		return MergeResult{}, err
+	}
+
+	err = mergeReview(tx, from, to)
+	if err != nil {
+		return MergeResult{}, err
+	}
+
+	// audit log entries are not moved, they would no longer match their hash
+	stmt := `
+        UPDATE user
+        SET status = ?, merged_into_id = ?
+        WHERE id = ?
+    `
+	_, err = tx.Exec(stmt, UserStatusMerged, to.Id, from.Id)
+	if err != nil {
+		return MergeResult{}, err
+	}
+	// users merged into the duplicate earlier follow it to the kept user
+	_, err = tx.Exec(`UPDATE user SET merged_into_id = ? WHERE merged_into_id = ?`, to.Id, from.Id)
+	if err != nil {
+		return MergeResult{}, err
+	}
+
+	to, err = get(tx, to.Id)
+	if err != nil {
+		return MergeResult{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return MergeResult{}, err
+	}
+
+	s.log.Printf("merged user %s into %s", from.Id, to.Id)
+	return MergeResult{
+		From:             from,
+		To:               to,
+		ConflictEventIds: conflicts,
+		Moved:            moved,
+	}, nil
+}
+
+// Moves the event responses, keeping the better spot when both users responded to the same event:
+// a spot off the waitlist, otherwise whichever response came first.
+func mergeResponses(tx *sqlx.Tx, fromId string, toId string) ([]string, error) {
+	stmt := `
+        SELECT t.event_id
+        FROM event_response AS t
+        INNER JOIN event_response AS f ON t.event_id = f.event_id
+        WHERE t.user_id = ? AND f.user_id = ?
+    `
+	conflicts := []string{}
+	err := tx.Select(&conflicts, stmt, toId, fromId)
+	if err != nil {
+		return []string{}, err
+	}
+
+	stmt = `
+        UPDATE event_response AS t
+        SET
+            attendee_count = f.attendee_count
+            , on_waitlist = f.on_waitlist
+            , created_at = f.created_at
+            , updated_at = f.updated_at
+        FROM event_response AS f
+        WHERE t.user_id = ? AND f.user_id = ? AND t.event_id = f.event_id
+            AND (
+                (t.on_waitlist AND NOT f.on_waitlist)
+                OR (t.on_waitlist = f.on_waitlist AND f.created_at < t.created_at)
+            )
+    `
+	_, err = tx.Exec(stmt, toId, fromId)
+	if err != nil {
+		return []string{}, err
+	}
+
+	_, err = tx.Exec(`UPDATE OR IGNORE event_response SET user_id = ? WHERE user_id = ?`, toId, fromId)
+	if err != nil {
+		return []string{}, err
+	}
+	_, err = tx.Exec(`DELETE FROM event_response WHERE user_id = ?`, fromId)
+	if err != nil {
+		return []string{}, err
+	}
+
+	return conflicts, nil
+}
+
+// Moves the payments, adding up the amounts when both users paid for the same event.
+func mergePayments(tx *sqlx.Tx, fromId string, toId string) error {
+	stmt := `
+        UPDATE event_payment AS t
+        SET amount = t.amount + f.amount
+        FROM event_payment AS f
+        WHERE t.user_id = ? AND f.user_id = ? AND t.event_id = f.event_id
+    `
+	_, err := tx.Exec(stmt, toId, fromId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE OR IGNORE event_payment SET user_id = ? WHERE user_id = ?`, toId, fromId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM event_payment WHERE user_id = ?`, fromId)
+	return err
+}
+
+// Keeps the review, and the status, of whichever user is further along.
+func mergeReview(tx *sqlx.Tx, from User, to User) error {
+	if reviewRank[from.Status] > reviewRank[to.Status] {
+		_, err := tx.Exec(`DELETE FROM user_review WHERE user_id = ?`, to.Id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE user_review SET user_id = ? WHERE user_id = ?`, to.Id, from.Id)
+		if err != nil {
+			return err
+		}
+		return setStatus(tx, to.Id, from.Status)
+	}
+
+	_, err := tx.Exec(`DELETE FROM user_review WHERE user_id = ?`, from.Id)
+	return err
+}
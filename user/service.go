@@ -3,17 +3,23 @@ package user
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	gonanoid "github.com/matoous/go-nanoid/v2"
 	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/event"
 	"github.com/mattfan00/jvbe/logger"
 )
 
 type service struct {
 	db  *db.DB
 	log logger.Logger
+	// issuer of the single provider users logged in with before issuers were recorded
+	legacyIssuer string
 }
 
 func NewService(db *db.DB) *service {
@@ -27,6 +33,12 @@ func (s *service) SetLogger(l logger.Logger) {
 	s.log = l
 }
 
+// Only logins from this issuer take over identities recorded before issuers were,
+// since subjects are only unique within an issuer.
+func (s *service) SetLegacyIssuer(issuer string) {
+	s.legacyIssuer = issuer
+}
+
 func (s *service) Get(id string) (User, error) {
 	tx, err := s.db.Beginx()
 	if err != nil {
@@ -38,19 +50,38 @@ func (s *service) Get(id string) (User, error) {
 	return u, err
 }
 
-func (s *service) HandleFromExternal(externalUser ExternalUser) (User, error) {
+// Lists the users that can still log in, i.e. not deleted or merged, by name.
+func (s *service) List() ([]User, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []User{}, err
+	}
+	defer tx.Rollback()
+
+	return list(tx)
+}
+
+// Gets or creates the user logging in. New and pending users are approved if they match one of the signup rules.
+func (s *service) HandleFromExternal(externalUser ExternalUser, signup Signup) (User, error) {
 	tx, err := s.db.Beginx()
 	if err != nil {
 		return User{}, err
 	}
 	defer tx.Rollback()
 
-	user, err := getByExternal(tx, externalUser.Id)
+	err = claimLegacyIdentity(tx, externalUser, s.legacyIssuer)
+	if err != nil {
+		return User{}, err
+	}
+
+	user, err := getByExternal(tx, externalUser.Issuer, externalUser.Id)
 	// if cant retrieve user, then need to create
 	if errors.Is(err, ErrNoUser) {
 		user, err = create(tx, CreateParams{
+			Issuer:     externalUser.Issuer,
 			ExternalId: externalUser.Id,
 			FullName:   externalUser.FullName,
+			Picture:    externalUser.Picture,
 		})
 		if err != nil {
 			return User{}, err
@@ -64,8 +95,46 @@ func (s *service) HandleFromExternal(externalUser ExternalUser) (User, error) {
 			return User{}, err
 		}
 
+		// signing up through a member's referral link counts as a confirmed vouch
+		if signup.ReferralToken != "" {
+			voucher, err := getReferrer(tx, signup.ReferralToken)
+			if err == nil {
+				err = createVouch(tx, user.Id, voucher.Id, true)
+			}
+			if err != nil && !errors.Is(err, ErrNoUser) {
+				return User{}, err
+			}
+		}
+
 	} else if err != nil {
 		return User{}, err
+	} else if user.MergedIntoId.Valid {
+		// logged in with the identity of a duplicate account, continue as the user it was merged into
+		user, err = get(tx, user.MergedIntoId.String)
+		if err != nil {
+			return User{}, err
+		}
+	} else {
+		user, err = syncExternal(tx, user, externalUser)
+		if err != nil {
+			return User{}, err
+		}
+	}
+
+	if user.Status == UserStatusInactive {
+		rule, err := matchApprovalRule(tx, externalUser, signup)
+		if err != nil {
+			return User{}, err
+		}
+
+		if rule != "" {
+			err = autoApprove(tx, user.Id, rule)
+			if err != nil {
+				return User{}, err
+			}
+			user.Status = UserStatusActive
+			s.log.Printf("auto approved user %s: %s", user.Id, rule)
+		}
 	}
 
 	err = tx.Commit()
@@ -77,7 +146,8 @@ func (s *service) HandleFromExternal(externalUser ExternalUser) (User, error) {
 }
 
 type CreateParams struct {
-	ExternalId string
+	Issuer     string
+	ExternalId string // subject of the first identity, the user has no identity if empty
 	FullName   string
 	Picture    string
 }
@@ -102,32 +172,120 @@ func (s *service) Create(p CreateParams) (User, error) {
 	return u, err
 }
 
-func (s *service) GetReview(userId string) (UserReview, error) {
+type UpdateProfileParams struct {
+	Id string
+	// An empty name goes back to the name from the IdP.
+	FullName          string
+	Bio               string
+	ContactInfo       string
+	ContactVisibility ContactVisibility
+	Timezone          string
+}
+
+func (s *service) UpdateProfile(p UpdateProfileParams) (User, error) {
+	s.log.Printf("user UpdateProfile params %+v", p)
+	p.FullName = strings.TrimSpace(p.FullName)
+	p.Bio = strings.TrimSpace(p.Bio)
+	p.ContactInfo = strings.TrimSpace(p.ContactInfo)
+	if len(p.FullName) > MaxFullNameLength {
+		return User{}, fmt.Errorf("name cannot be longer than %d characters", MaxFullNameLength)
+	}
+	if len(p.Bio) > MaxBioLength {
+		return User{}, fmt.Errorf("bio cannot be longer than %d characters", MaxBioLength)
+	}
+	if len(p.ContactInfo) > MaxContactInfoLength {
+		return User{}, fmt.Errorf("contact info cannot be longer than %d characters", MaxContactInfoLength)
+	}
+	if p.ContactVisibility < ContactVisibilityHidden || p.ContactVisibility > ContactVisibilityEveryone {
+		return User{}, errors.New("invalid contact visibility")
+	}
+	if _, err := event.LoadLocation(p.Timezone); err != nil {
+		return User{}, err
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return User{}, err
+	}
+	defer tx.Rollback()
+
+	u, err := get(tx, p.Id)
+	if err != nil {
+		return User{}, err
+	}
+
+	fullName, overridden := p.FullName, true
+	if fullName == "" || fullName == u.ExternalFullName {
+		fullName, overridden = u.ExternalFullName, false
+	}
+
 	stmt := `
-        SELECT user_id, comment FROM user_review
-        WHERE user_id = ?
+        UPDATE user
+        SET full_name = ?, full_name_overridden = ?, bio = ?, contact_info = ?, contact_visibility = ?, timezone = ?
+        WHERE id = ?
     `
-	args := []any{userId}
+	args := []any{fullName, overridden, p.Bio, p.ContactInfo, p.ContactVisibility, p.Timezone, p.Id}
 
-	var userReview UserReview
-	err := s.db.Get(&userReview, stmt, args...)
-	return userReview, err
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return User{}, err
+	}
+
+	u, err = get(tx, p.Id)
+	if err != nil {
+		return User{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return User{}, err
+	}
+
+	return u, nil
 }
 
+func (s *service) GetReview(userId string) (UserReview, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return UserReview{}, err
+	}
+	defer tx.Rollback()
+
+	return getReview(tx, userId)
+}
+
+// Lists the users waiting to be reviewed.
 func (s *service) ListReviews() ([]UserReview, error) {
 	stmt := `
         SELECT 
             ur.user_id, ur.created_at, ur.comment 
-            , u.full_name AS user_full_name
+            , u.full_name AS user_full_name, u.status AS user_status
         FROM user_review ur
         INNER JOIN user u ON ur.user_id = u.id
-        WHERE is_approved = 0
+        WHERE u.status = ?
         ORDER BY ur.created_at
     `
 
-	var u []UserReview
-	err := s.db.Select(&u, stmt)
-	return u, err
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []UserReview{}, err
+	}
+	defer tx.Rollback()
+
+	var reviews []UserReview
+	err = tx.Select(&reviews, stmt, UserStatusInactive)
+	if err != nil {
+		return []UserReview{}, err
+	}
+
+	for i := range reviews {
+		reviews[i].Vouches, err = listVouches(tx, reviews[i].UserId)
+		if err != nil {
+			return []UserReview{}, err
+		}
+	}
+
+	return reviews, nil
 }
 
 type UpdateReviewParams struct {
@@ -156,53 +314,242 @@ func (s *service) UpdateReview(p UpdateReviewParams) error {
 	return nil
 }
 
-func (s *service) ApproveReview(userId string) error {
-	if userId == "" {
-		return errors.New("invalid user")
+type ReviewParams struct {
+	UserId     string
+	ReviewerId string
+	Comment    string // required when rejecting or suspending
+	// confirmed vouches a pending user needs before they can be approved
+	RequiredVouches int
+}
+
+// Approves a pending or rejected user, or reinstates a suspended one.
+func (s *service) ApproveReview(p ReviewParams) error {
+	s.log.Printf("user ApproveReview params %+v", p)
+	return s.decide(p, []UserStatus{UserStatusInactive, UserStatusRejected, UserStatusSuspended}, UserStatusActive)
+}
+
+func (s *service) RejectReview(p ReviewParams) error {
+	s.log.Printf("user RejectReview params %+v", p)
+	if strings.TrimSpace(p.Comment) == "" {
+		return ErrReviewCommentMissing
 	}
+	return s.decide(p, []UserStatus{UserStatusInactive}, UserStatusRejected)
+}
 
+// Blocks an active user from the application. Withdrawing their responses is left to the caller.
+func (s *service) Suspend(p ReviewParams) error {
+	s.log.Printf("user Suspend params %+v", p)
+	if strings.TrimSpace(p.Comment) == "" {
+		return ErrReviewCommentMissing
+	}
+	if p.UserId == p.ReviewerId {
+		return errors.New("you cannot suspend yourself")
+	}
+	return s.decide(p, []UserStatus{UserStatusActive}, UserStatusSuspended)
+}
+
+// Moves a rejected user back to pending once ReapplyCooldown has passed since the rejection.
+func (s *service) Reapply(userId string) error {
+	s.log.Printf("user Reapply user %s", userId)
 	tx, err := s.db.Beginx()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	review, err := getReview(tx, userId)
+	if err != nil {
+		return err
+	}
+	if !review.IsRejected() {
+		return ErrInvalidStatus
+	}
+	if !review.CanReapply(time.Now()) {
+		return ErrReapplyCooldown
+	}
+
 	stmt := `
         UPDATE user_review
-        SET is_approved = 1, reviewed_at = ?
+        SET created_at = ?
         WHERE user_id = ?
     `
-	args := []any{time.Now().UTC(), userId}
+	_, err = tx.Exec(stmt, time.Now().UTC(), userId)
+	if err != nil {
+		return err
+	}
 
-	_, err = tx.Exec(stmt, args...)
+	err = setStatus(tx, userId, UserStatusInactive)
 	if err != nil {
 		return err
 	}
-	s.log.Printf("approved user review for %s", userId)
 
-	stmt = `
+	return tx.Commit()
+}
+
+// Deletes the user's personal data. The user row is anonymized rather than removed so that
+// past attendance, comments and the audit log still add up. Audit log descriptions are kept as they were recorded,
+// rewriting them would break the hash chain.
+//
+// Returns the withdrawn responses to upcoming events so the users moved off the waitlist can be notified.
+func (s *service) Delete(id string) ([]event.WithdrawnResponse, error) {
+	s.log.Printf("user Delete %s", id)
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []event.WithdrawnResponse{}, err
+	}
+	defer tx.Rollback()
+
+	u, err := get(tx, id)
+	if err != nil {
+		return []event.WithdrawnResponse{}, err
+	}
+	if u.IsDeleted() {
+		return []event.WithdrawnResponse{}, ErrInvalidStatus
+	}
+
+	// free up their spots before the account is gone so the waitlist can move up
+	withdrawn, err := event.WithdrawUpcomingResponsesTx(tx, id)
+	if err != nil {
+		return []event.WithdrawnResponse{}, err
+	}
+
+	stmt := `
         UPDATE user
-        SET status = ?
+        SET
+            full_name = ?
+            , external_id = ?
+            , picture = NULL
+            , bio = ''
+            , contact_info = ''
+            , contact_visibility = ?
+            , external_full_name = ''
+            , full_name_overridden = FALSE
+            , timezone = ''
+            , status = ?
         WHERE id = ?
     `
-	args = []any{UserStatusActive, userId}
+	args := []any{
+		DeletedFullName,
+		"deleted:" + id,
+		ContactVisibilityHidden,
+		UserStatusDeleted,
+		id,
+	}
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return []event.WithdrawnResponse{}, err
+	}
+
+	// rows that only matter while the user has an account
+	stmts := []string{
+		`DELETE FROM user_identity WHERE user_id = ?`,
+		`DELETE FROM user_referral WHERE user_id = ?`,
+		`DELETE FROM user_review WHERE user_id = ?`,
+		`DELETE FROM user_vouch WHERE user_id = ?`,
+		`DELETE FROM user_vouch WHERE voucher_id = ?`,
+		`DELETE FROM user_group_member WHERE user_id = ?`,
+		`DELETE FROM tag_follow WHERE user_id = ?`,
+		`DELETE FROM notification WHERE user_id = ?`,
+	}
+	for _, stmt := range stmts {
+		_, err = tx.Exec(stmt, id)
+		if err != nil {
+			return []event.WithdrawnResponse{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return []event.WithdrawnResponse{}, err
+	}
+
+	return withdrawn, nil
+}
+
+// Moves the user from one of the statuses in from to the status to, recording the reviewer's decision.
+func (s *service) decide(p ReviewParams, from []UserStatus, to UserStatus) error {
+	if p.UserId == "" {
+		return errors.New("invalid user")
+	}
+	p.Comment = strings.TrimSpace(p.Comment)
+	if len(p.Comment) > MaxReviewCommentLength {
+		return fmt.Errorf("comment cannot be longer than %d characters", MaxReviewCommentLength)
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	u, err := get(tx, p.UserId)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(from, u.Status) {
+		return ErrInvalidStatus
+	}
+	// reinstating a suspended member does not need vouches, they were approved before
+	pending := u.Status == UserStatusInactive || u.Status == UserStatusRejected
+	if to == UserStatusActive && pending && p.RequiredVouches > 0 {
+		vouches, err := listVouches(tx, p.UserId)
+		if err != nil {
+			return err
+		}
+		if countConfirmed(vouches) < p.RequiredVouches {
+			return ErrNotEnoughVouches
+		}
+	}
+
+	stmt := `
+        INSERT INTO user_review (user_id, created_at, reviewed_at, is_approved, reviewer_id, reviewer_comment)
+        VALUES (?, ?, ?, ?, ?, ?)
+        ON CONFLICT (user_id) DO UPDATE SET
+            reviewed_at = excluded.reviewed_at
+            , is_approved = excluded.is_approved
+            , reviewer_id = excluded.reviewer_id
+            , reviewer_comment = excluded.reviewer_comment
+            , approval_rule = NULL
+    `
+	now := time.Now().UTC()
+	args := []any{
+		p.UserId,
+		now,
+		now,
+		to == UserStatusActive,
+		sql.NullString{
+			String: p.ReviewerId,
+			Valid:  p.ReviewerId != "",
+		},
+		sql.NullString{
+			String: p.Comment,
+			Valid:  p.Comment != "",
+		},
+	}
 
 	_, err = tx.Exec(stmt, args...)
 	if err != nil {
 		return err
 	}
-	s.log.Printf("set user %s to active status", userId)
 
-	if err = tx.Commit(); err != nil {
+	err = setStatus(tx, p.UserId, to)
+	if err != nil {
 		return err
 	}
+	s.log.Printf("set user %s to status %d", p.UserId, to)
 
-	return nil
+	return tx.Commit()
 }
 
+const userColumns = `
+    id, full_name, external_id, created_at, status
+    , COALESCE(picture, '') AS picture, bio, contact_info, contact_visibility
+    , external_full_name, full_name_overridden, timezone, merged_into_id
+`
+
 func get(tx *sqlx.Tx, id string) (User, error) {
 	stmt := `
-        SELECT id, full_name, external_id, created_at, status FROM user
+        SELECT ` + userColumns + ` FROM user
         WHERE id = ?
     `
 	args := []any{id}
@@ -218,24 +565,17 @@ func get(tx *sqlx.Tx, id string) (User, error) {
 	return user, nil
 }
 
-func getByExternal(tx *sqlx.Tx, externalId string) (User, error) {
+func list(tx *sqlx.Tx) ([]User, error) {
 	stmt := `
-        SELECT 
-            id, full_name, external_id, created_at, status
-        FROM user
-        WHERE external_id = ?
+        SELECT ` + userColumns + ` FROM user
+        WHERE status NOT IN (?, ?)
+        ORDER BY full_name
     `
-	args := []any{externalId}
+	args := []any{UserStatusDeleted, UserStatusMerged}
 
-	var user User
-	err := tx.Get(&user, stmt, args...)
-	if errors.Is(err, sql.ErrNoRows) {
-		return User{}, ErrNoUser
-	} else if err != nil {
-		return User{}, err
-	}
-
-	return user, nil
+	users := []User{}
+	err := tx.Select(&users, stmt, args...)
+	return users, err
 }
 
 func create(tx *sqlx.Tx, p CreateParams) (User, error) {
@@ -245,15 +585,20 @@ func create(tx *sqlx.Tx, p CreateParams) (User, error) {
 	}
 
 	stmt := `
-        INSERT INTO user (id, full_name, external_id, created_at, status)
-        VALUES (?, ?, ?, ?, ?)
+        INSERT INTO user (id, full_name, external_full_name, external_id, created_at, status, picture)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
     `
 	args := []any{
 		newId,
 		p.FullName,
+		p.FullName,
 		p.ExternalId,
 		time.Now().UTC(),
 		UserStatusInactive,
+		sql.NullString{
+			String: p.Picture,
+			Valid:  p.Picture != "",
+		},
 	}
 
 	_, err = tx.Exec(stmt, args...)
@@ -261,6 +606,13 @@ func create(tx *sqlx.Tx, p CreateParams) (User, error) {
 		return User{}, err
 	}
 
+	if p.ExternalId != "" {
+		err = createIdentity(tx, newId, p.Issuer, p.ExternalId)
+		if err != nil {
+			return User{}, err
+		}
+	}
+
 	newUser, err := get(tx, newId)
 	if err != nil {
 		return User{}, err
@@ -269,6 +621,64 @@ func create(tx *sqlx.Tx, p CreateParams) (User, error) {
 	return newUser, nil
 }
 
+// Copies changes from the IdP onto the user. The name is only copied if the user has not overridden it.
+func syncExternal(tx *sqlx.Tx, u User, eu ExternalUser) (User, error) {
+	stmt := `
+        UPDATE user
+        SET
+            external_full_name = ?
+            , full_name = CASE WHEN full_name_overridden THEN full_name ELSE ? END
+            , picture = ?
+        WHERE id = ?
+    `
+	args := []any{
+		eu.FullName,
+		eu.FullName,
+		sql.NullString{
+			String: eu.Picture,
+			Valid:  eu.Picture != "",
+		},
+		u.Id,
+	}
+
+	_, err := tx.Exec(stmt, args...)
+	if err != nil {
+		return User{}, err
+	}
+
+	return get(tx, u.Id)
+}
+
+func getReview(tx *sqlx.Tx, userId string) (UserReview, error) {
+	stmt := `
+        SELECT
+            ur.user_id, ur.created_at, ur.reviewed_at, ur.comment, ur.is_approved
+            , ur.reviewer_id, ur.reviewer_comment, r.full_name AS reviewer_full_name, ur.approval_rule
+            , u.full_name AS user_full_name, u.status AS user_status
+        FROM user_review AS ur
+        INNER JOIN user AS u ON ur.user_id = u.id
+        LEFT JOIN user AS r ON ur.reviewer_id = r.id
+        WHERE ur.user_id = ?
+    `
+	args := []any{userId}
+
+	var userReview UserReview
+	err := tx.Get(&userReview, stmt, args...)
+	return userReview, err
+}
+
+func setStatus(tx *sqlx.Tx, userId string, status UserStatus) error {
+	stmt := `
+        UPDATE user
+        SET status = ?
+        WHERE id = ?
+    `
+	args := []any{status, userId}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
+
 func updateReview(tx *sqlx.Tx, p UpdateReviewParams) error {
 	if len(p.Comment) > 100 {
 		return errors.New("comment too long")
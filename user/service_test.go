@@ -0,0 +1,706 @@
+package user_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattfan00/jvbe/auditlog"
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/group"
+	"github.com/mattfan00/jvbe/user"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleFromExternal(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	userService := user.NewService(db)
+
+	u, err := userService.HandleFromExternal(user.ExternalUser{
+		Id:       "external",
+		FullName: "Idp Name",
+		Picture:  "https://example.com/a.png",
+	}, user.Signup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Idp Name", u.FullName)
+	assert.Equal(t, "https://example.com/a.png", u.Picture)
+
+	t.Run("syncs fields not overridden", func(t *testing.T) {
+		u, err := userService.HandleFromExternal(user.ExternalUser{
+			Id:       "external",
+			FullName: "New Idp Name",
+			Picture:  "https://example.com/b.png",
+		}, user.Signup{})
+		assert.NoError(t, err)
+		assert.Equal(t, "New Idp Name", u.FullName)
+		assert.Equal(t, "https://example.com/b.png", u.Picture)
+	})
+
+	t.Run("keeps overridden name", func(t *testing.T) {
+		_, err := userService.UpdateProfile(user.UpdateProfileParams{
+			Id:       u.Id,
+			FullName: "Nickname",
+			Bio:      " bio ",
+		})
+		assert.NoError(t, err)
+
+		u, err := userService.HandleFromExternal(user.ExternalUser{
+			Id:       "external",
+			FullName: "Another Idp Name",
+			Picture:  "https://example.com/c.png",
+		}, user.Signup{})
+		assert.NoError(t, err)
+		assert.Equal(t, "Nickname", u.FullName)
+		assert.True(t, u.FullNameOverridden)
+		assert.Equal(t, "Another Idp Name", u.ExternalFullName)
+		assert.Equal(t, "https://example.com/c.png", u.Picture)
+		assert.Equal(t, "bio", u.Bio)
+	})
+
+	t.Run("blank name goes back to the idp name", func(t *testing.T) {
+		u, err := userService.UpdateProfile(user.UpdateProfileParams{
+			Id: u.Id,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "Another Idp Name", u.FullName)
+		assert.False(t, u.FullNameOverridden)
+	})
+}
+
+func TestUpdateProfile(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	userService := user.NewService(db)
+
+	u, err := userService.Create(user.CreateParams{FullName: "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = userService.UpdateProfile(user.UpdateProfileParams{
+		Id:                u.Id,
+		ContactVisibility: 5,
+	})
+	assert.Error(t, err)
+
+	_, err = userService.UpdateProfile(user.UpdateProfileParams{
+		Id:  u.Id,
+		Bio: strings.Repeat("a", user.MaxBioLength+1),
+	})
+	assert.Error(t, err)
+
+	u, err = userService.UpdateProfile(user.UpdateProfileParams{
+		Id:                u.Id,
+		ContactInfo:       "555-1234",
+		ContactVisibility: user.ContactVisibilityGroupMembers,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "555-1234", u.ContactInfo)
+	assert.Equal(t, user.ContactVisibilityGroupMembers, u.ContactVisibility)
+	assert.Equal(t, "name", u.FullName)
+}
+
+func TestReview(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	userService := user.NewService(db)
+
+	reviewer, err := userService.Create(user.CreateParams{FullName: "Reviewer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := userService.Create(user.CreateParams{FullName: "Applicant"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("reject requires a comment", func(t *testing.T) {
+		err := userService.RejectReview(user.ReviewParams{UserId: u.Id, ReviewerId: reviewer.Id})
+		assert.ErrorIs(t, err, user.ErrReviewCommentMissing)
+	})
+
+	t.Run("reject records the reviewer", func(t *testing.T) {
+		err := userService.RejectReview(user.ReviewParams{UserId: u.Id, ReviewerId: reviewer.Id, Comment: "who are you?"})
+		assert.NoError(t, err)
+
+		review, err := userService.GetReview(u.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, user.UserStatusRejected, review.UserStatus)
+		assert.Equal(t, "Reviewer", review.ReviewerFullName.String)
+		assert.Equal(t, "who are you?", review.ReviewerComment.String)
+
+		reviews, err := userService.ListReviews()
+		assert.NoError(t, err)
+		for _, r := range reviews {
+			assert.NotEqual(t, u.Id, r.UserId)
+		}
+	})
+
+	t.Run("reapply waits for the cooldown", func(t *testing.T) {
+		err := userService.Reapply(u.Id)
+		assert.ErrorIs(t, err, user.ErrReapplyCooldown)
+
+		_, err = db.Exec(`UPDATE user_review SET reviewed_at = ? WHERE user_id = ?`, time.Now().Add(-user.ReapplyCooldown).UTC(), u.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = userService.Reapply(u.Id)
+		assert.NoError(t, err)
+
+		got, err := userService.Get(u.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, user.UserStatusInactive, got.Status)
+
+		err = userService.Reapply(u.Id)
+		assert.ErrorIs(t, err, user.ErrInvalidStatus)
+	})
+
+	t.Run("suspend and reinstate", func(t *testing.T) {
+		err := userService.Suspend(user.ReviewParams{UserId: u.Id, ReviewerId: reviewer.Id, Comment: "not active yet"})
+		assert.ErrorIs(t, err, user.ErrInvalidStatus)
+
+		err = userService.ApproveReview(user.ReviewParams{UserId: u.Id, ReviewerId: reviewer.Id})
+		assert.NoError(t, err)
+
+		err = userService.Suspend(user.ReviewParams{UserId: u.Id, ReviewerId: reviewer.Id})
+		assert.ErrorIs(t, err, user.ErrReviewCommentMissing)
+
+		err = userService.Suspend(user.ReviewParams{UserId: u.Id, ReviewerId: reviewer.Id, Comment: "no shows"})
+		assert.NoError(t, err)
+
+		got, err := userService.Get(u.Id)
+		assert.NoError(t, err)
+		assert.True(t, got.IsSuspended())
+
+		err = userService.ApproveReview(user.ReviewParams{UserId: u.Id, ReviewerId: reviewer.Id})
+		assert.NoError(t, err)
+
+		got, err = userService.Get(u.Id)
+		assert.NoError(t, err)
+		assert.True(t, got.IsActive())
+	})
+}
+
+func TestAutoApprove(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	userService := user.NewService(db)
+	groupService := group.NewService(db)
+
+	member, err := userService.Create(user.CreateParams{FullName: "Member"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := userService.ApproveReview(user.ReviewParams{UserId: member.Id}); err != nil {
+		t.Fatal(err)
+	}
+	groupId, err := groupService.CreateAndAddMember(group.CreateParams{CreatorId: member.Id, Name: "Trusted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := groupService.Get(groupId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := userService.ReferralToken(member.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := user.AutoApproveRules{
+		InviteGroupIds: []string{groupId},
+		EmailDomains:   []string{"Example.com"},
+		Vouch:          true,
+	}
+
+	tests := []struct {
+		name         string
+		externalUser user.ExternalUser
+		signup       user.Signup
+		expectedRule string
+	}{
+		{
+			name:         "no rule",
+			externalUser: user.ExternalUser{Email: "a@other.com", EmailVerified: true},
+			signup:       user.Signup{Rules: rules},
+		},
+		{
+			name:         "invite",
+			signup:       user.Signup{InviteId: g.InviteId, Rules: rules},
+			expectedRule: "invite link of Trusted",
+		},
+		{
+			name:   "invite of group without rule",
+			signup: user.Signup{InviteId: g.InviteId},
+		},
+		{
+			name:         "email domain",
+			externalUser: user.ExternalUser{Email: "a@example.com", EmailVerified: true},
+			signup:       user.Signup{Rules: rules},
+			expectedRule: "email domain example.com",
+		},
+		{
+			name:         "unverified email",
+			externalUser: user.ExternalUser{Email: "a@example.com"},
+			signup:       user.Signup{Rules: rules},
+		},
+		{
+			name:         "vouch",
+			signup:       user.Signup{ReferralToken: token, Rules: rules},
+			expectedRule: "vouched for by Member",
+		},
+		{
+			name:   "referral link made up from a user id",
+			signup: user.Signup{ReferralToken: member.Id, Rules: rules},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.externalUser.Id = fmt.Sprintf("external-%d", i)
+			u, err := userService.HandleFromExternal(tt.externalUser, tt.signup)
+			assert.NoError(t, err)
+
+			review, err := userService.GetReview(u.Id)
+			assert.NoError(t, err)
+			if tt.expectedRule == "" {
+				assert.Equal(t, user.UserStatusInactive, u.Status)
+				assert.False(t, review.ApprovalRule.Valid)
+			} else {
+				assert.Equal(t, user.UserStatusActive, u.Status)
+				assert.Equal(t, tt.expectedRule, review.ApprovalRule.String)
+			}
+		})
+	}
+
+	t.Run("pending user logging in again", func(t *testing.T) {
+		pending := user.ExternalUser{Id: "pending"}
+		_, err := userService.HandleFromExternal(pending, user.Signup{})
+		assert.NoError(t, err)
+		u, err := userService.HandleFromExternal(pending, user.Signup{ReferralToken: token, Rules: rules})
+		assert.NoError(t, err)
+		assert.Equal(t, user.UserStatusActive, u.Status)
+	})
+}
+
+func TestVouch(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	userService := user.NewService(db)
+
+	member, err := userService.Create(user.CreateParams{FullName: "Jane Member"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := userService.ApproveReview(user.ReviewParams{UserId: member.Id}); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := userService.Create(user.CreateParams{FullName: "Pending Member"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	applicant, err := userService.HandleFromExternal(user.ExternalUser{Id: "applicant"}, user.Signup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("voucher must be an active member", func(t *testing.T) {
+		_, err := userService.RequestVouch(user.RequestVouchParams{UserId: applicant.Id, VoucherName: pending.FullName})
+		assert.ErrorIs(t, err, user.ErrNoVoucher)
+	})
+
+	t.Run("approval waits for required vouches", func(t *testing.T) {
+		v, err := userService.RequestVouch(user.RequestVouchParams{UserId: applicant.Id, VoucherName: " jane member "})
+		assert.NoError(t, err)
+		assert.Equal(t, member.Id, v.VoucherId)
+		assert.False(t, v.IsConfirmed())
+
+		_, err = userService.RequestVouch(user.RequestVouchParams{UserId: applicant.Id, VoucherName: "Jane Member"})
+		assert.ErrorIs(t, err, user.ErrAlreadyVouchedFor)
+
+		err = userService.ApproveReview(user.ReviewParams{UserId: applicant.Id, RequiredVouches: 1})
+		assert.ErrorIs(t, err, user.ErrNotEnoughVouches)
+
+		err = userService.ConfirmVouch(applicant.Id, member.Id)
+		assert.NoError(t, err)
+
+		reviews, err := userService.ListReviews()
+		assert.NoError(t, err)
+		assert.Len(t, reviews, 1)
+		assert.Equal(t, 1, reviews[0].ConfirmedVouches())
+
+		err = userService.ApproveReview(user.ReviewParams{UserId: applicant.Id, RequiredVouches: 1})
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejected users need the required vouches too", func(t *testing.T) {
+		rejected, err := userService.HandleFromExternal(user.ExternalUser{Id: "rejected"}, user.Signup{})
+		assert.NoError(t, err)
+		err = userService.RejectReview(user.ReviewParams{UserId: rejected.Id, ReviewerId: member.Id, Comment: "who is this"})
+		assert.NoError(t, err)
+
+		err = userService.ApproveReview(user.ReviewParams{UserId: rejected.Id, RequiredVouches: 1})
+		assert.ErrorIs(t, err, user.ErrNotEnoughVouches)
+	})
+
+	t.Run("referral link counts as a confirmed vouch", func(t *testing.T) {
+		token, err := userService.ReferralToken(member.Id)
+		assert.NoError(t, err)
+		again, err := userService.ReferralToken(member.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, token, again)
+
+		u, err := userService.HandleFromExternal(user.ExternalUser{Id: "referred"}, user.Signup{ReferralToken: token})
+		assert.NoError(t, err)
+		assert.Equal(t, user.UserStatusInactive, u.Status)
+
+		vouches, err := userService.ListVouches(u.Id)
+		assert.NoError(t, err)
+		assert.Len(t, vouches, 1)
+		assert.True(t, vouches[0].IsConfirmed())
+
+		err = userService.DeleteVouch(u.Id, member.Id)
+		assert.NoError(t, err)
+		vouches, err = userService.ListVouches(u.Id)
+		assert.NoError(t, err)
+		assert.Len(t, vouches, 0)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	userService := user.NewService(db)
+	groupService := group.NewService(db)
+	eventService := event.NewService(db)
+
+	u, err := userService.HandleFromExternal(user.ExternalUser{Id: "external", FullName: "Real Name"}, user.Signup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = userService.UpdateProfile(user.UpdateProfileParams{Id: u.Id, Bio: "bio", ContactInfo: "555-5555"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := userService.ApproveReview(user.ReviewParams{UserId: u.Id}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = groupService.CreateAndAddMember(group.CreateParams{CreatorId: u.Id, Name: "group"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitlisted, err := userService.Create(user.CreateParams{FullName: "Waitlisted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventId, err := eventService.Create(event.CreateParams{
+		Name:      "event",
+		CreatorId: waitlisted.Id,
+		Start:     time.Now().Add(24 * time.Hour),
+		Capacity:  1,
+		Location:  "location",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []event.HandleResponseParams{
+		{UserId: u.Id, Id: eventId, AttendeeCount: 1},
+		{UserId: waitlisted.Id, Id: eventId, AttendeeCount: 1},
+	} {
+		if err := eventService.HandleResponse(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	withdrawn, err := userService.Delete(u.Id)
+	assert.NoError(t, err)
+	assert.Len(t, withdrawn, 1)
+	assert.Equal(t, eventId, withdrawn[0].EventId)
+	assert.Len(t, withdrawn[0].Moved, 1)
+	assert.Equal(t, waitlisted.Id, withdrawn[0].Moved[0].UserId)
+
+	got, err := userService.Get(u.Id)
+	assert.NoError(t, err)
+	assert.True(t, got.IsDeleted())
+	assert.Equal(t, user.DeletedFullName, got.FullName)
+	assert.Equal(t, "", got.Bio)
+	assert.Equal(t, "", got.ContactInfo)
+	assert.Equal(t, "", got.ExternalFullName)
+
+	groups, err := groupService.ListForUser(u.Id)
+	assert.NoError(t, err)
+	assert.Len(t, groups, 0)
+	groups, err = groupService.List()
+	assert.NoError(t, err)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, 0, groups[0].TotalMemberCount)
+
+	_, err = userService.Delete(u.Id)
+	assert.ErrorIs(t, err, user.ErrInvalidStatus)
+
+	// logging in again starts a new account
+	again, err := userService.HandleFromExternal(user.ExternalUser{Id: "external", FullName: "Real Name"}, user.Signup{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, u.Id, again.Id)
+	assert.Equal(t, user.UserStatusInactive, again.Status)
+}
+
+func TestMerge(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	userService := user.NewService(db)
+	groupService := group.NewService(db)
+	eventService := event.NewService(db)
+	auditlogService := auditlog.NewService(db)
+
+	to, err := userService.HandleFromExternal(user.ExternalUser{Id: "google", FullName: "Kept"}, user.Signup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := userService.ApproveReview(user.ReviewParams{UserId: to.Id}); err != nil {
+		t.Fatal(err)
+	}
+	from, err := userService.HandleFromExternal(user.ExternalUser{Id: "email", FullName: "Duplicate"}, user.Signup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groupId, err := groupService.CreateAndAddMember(group.CreateParams{CreatorId: to.Id, Name: "group"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO user_group_member (group_id, user_id, created_at) VALUES (?, ?, ?)`, groupId, from.Id, time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := userService.Create(user.CreateParams{FullName: "Other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newEvent := func(name string, capacity int) string {
+		id, err := eventService.Create(event.CreateParams{
+			Name:      name,
+			CreatorId: to.Id,
+			Start:     time.Now().Add(24 * time.Hour),
+			Capacity:  capacity,
+			Location:  "location",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	both := newEvent("both", 1)
+	onlyFrom := newEvent("only from", 1)
+	freed := newEvent("freed", 2)
+	// the kept user is waitlisted behind the duplicate, so the duplicate's spot is kept
+	for _, p := range []event.HandleResponseParams{
+		{UserId: from.Id, Id: both, AttendeeCount: 1},
+		{UserId: to.Id, Id: both, AttendeeCount: 1},
+		{UserId: from.Id, Id: onlyFrom, AttendeeCount: 1},
+		{UserId: from.Id, Id: freed, AttendeeCount: 1},
+		{UserId: to.Id, Id: freed, AttendeeCount: 1},
+		{UserId: other.Id, Id: freed, AttendeeCount: 1},
+	} {
+		if err := eventService.HandleResponse(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, id := range []string{to.Id, from.Id, to.Id} {
+		if err := auditlogService.Create(id, "entry"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := userService.Merge(user.MergeParams{FromId: from.Id, ToId: to.Id})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{both, freed}, res.ConflictEventIds)
+	assert.True(t, res.To.IsActive())
+	// only one response is kept, so the waitlisted user moves up in the same transaction
+	assert.Len(t, res.Moved[freed], 1)
+	assert.Equal(t, other.Id, res.Moved[freed][0].UserId)
+	assert.False(t, res.Moved[freed][0].OnWaitlist)
+
+	responses, err := eventService.ListResponses(both)
+	assert.NoError(t, err)
+	assert.Len(t, responses, 1)
+	assert.Equal(t, to.Id, responses[0].UserId)
+	assert.False(t, responses[0].OnWaitlist)
+
+	responses, err = eventService.ListResponses(onlyFrom)
+	assert.NoError(t, err)
+	assert.Len(t, responses, 1)
+	assert.Equal(t, to.Id, responses[0].UserId)
+
+	groups, err := groupService.ListForUser(to.Id)
+	assert.NoError(t, err)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, 1, groups[0].TotalMemberCount)
+
+	// entries keep the user they were recorded by but are listed under the kept user
+	entries, _, err := auditlogService.List(auditlog.ListFilter{UserId: to.Id})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	for _, e := range entries {
+		assert.Equal(t, to.Id, e.UserId)
+	}
+	var recorded int
+	err = db.Get(&recorded, `SELECT COUNT(*) FROM audit_log WHERE user_id = ?`, from.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, recorded)
+	broken, err := auditlogService.Verify()
+	assert.NoError(t, err)
+	assert.Nil(t, broken)
+
+	merged, err := userService.Get(from.Id)
+	assert.NoError(t, err)
+	assert.True(t, merged.IsMerged())
+
+	// logging in as the duplicate continues as the kept user
+	u, err := userService.HandleFromExternal(user.ExternalUser{Id: "email", FullName: "Duplicate"}, user.Signup{})
+	assert.NoError(t, err)
+	assert.Equal(t, to.Id, u.Id)
+
+	_, err = userService.Merge(user.MergeParams{FromId: from.Id, ToId: to.Id})
+	assert.ErrorIs(t, err, user.ErrInvalidStatus)
+}
+
+func TestMergeKeepsSuspension(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	userService := user.NewService(db)
+
+	reviewer, err := userService.Create(user.CreateParams{FullName: "Reviewer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := userService.HandleFromExternal(user.ExternalUser{Id: "suspended"}, user.Signup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := userService.ApproveReview(user.ReviewParams{UserId: from.Id}); err != nil {
+		t.Fatal(err)
+	}
+	if err := userService.Suspend(user.ReviewParams{UserId: from.Id, ReviewerId: reviewer.Id, Comment: "no shows"}); err != nil {
+		t.Fatal(err)
+	}
+	to, err := userService.HandleFromExternal(user.ExternalUser{Id: "new"}, user.Signup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := userService.Merge(user.MergeParams{FromId: from.Id, ToId: to.Id})
+	assert.NoError(t, err)
+	assert.True(t, res.To.IsSuspended())
+
+	review, err := userService.GetReview(to.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "no shows", review.ReviewerComment.String)
+}
+
+func TestIdentity(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	userService := user.NewService(db)
+
+	u, err := userService.HandleFromExternal(user.ExternalUser{Issuer: "https://a.example.com", Id: "first"}, user.Signup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := userService.HandleFromExternal(user.ExternalUser{Issuer: "https://a.example.com", Id: "other"}, user.Signup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := user.ExternalUser{Issuer: "https://b.example.com", Id: "first"}
+	err = userService.LinkIdentity(u.Id, second)
+	assert.NoError(t, err)
+	assert.NoError(t, userService.LinkIdentity(u.Id, second), "linking again is a no-op")
+
+	identities, err := userService.ListIdentities(u.Id)
+	assert.NoError(t, err)
+	assert.Len(t, identities, 2)
+
+	t.Run("logs in with either identity", func(t *testing.T) {
+		lu, err := userService.HandleFromExternal(second, user.Signup{})
+		assert.NoError(t, err)
+		assert.Equal(t, u.Id, lu.Id)
+	})
+
+	t.Run("cannot link an identity of another user", func(t *testing.T) {
+		err := userService.LinkIdentity(u.Id, user.ExternalUser{Issuer: "https://a.example.com", Id: "other"})
+		assert.ErrorIs(t, err, user.ErrIdentityInUse)
+	})
+
+	t.Run("cannot unlink the last identity", func(t *testing.T) {
+		err := userService.UnlinkIdentity(u.Id, "https://a.example.com", "first")
+		assert.NoError(t, err)
+		err = userService.UnlinkIdentity(u.Id, "https://b.example.com", "first")
+		assert.ErrorIs(t, err, user.ErrLastIdentity)
+
+		err = userService.UnlinkIdentity(other.Id, "https://a.example.com", "other")
+		assert.ErrorIs(t, err, user.ErrLastIdentity)
+	})
+
+	t.Run("claims identities without an issuer", func(t *testing.T) {
+		userService.SetLegacyIssuer("https://a.example.com")
+		legacy, err := userService.Create(user.CreateParams{FullName: "Legacy", ExternalId: "legacy"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the same subject at another issuer is someone else
+		other, err := userService.HandleFromExternal(user.ExternalUser{Issuer: "https://b.example.com", Id: "legacy"}, user.Signup{})
+		assert.NoError(t, err)
+		assert.NotEqual(t, legacy.Id, other.Id)
+
+		lu, err := userService.HandleFromExternal(user.ExternalUser{Issuer: "https://a.example.com", Id: "legacy"}, user.Signup{})
+		assert.NoError(t, err)
+		assert.Equal(t, legacy.Id, lu.Id)
+
+		identities, err := userService.ListIdentities(legacy.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://a.example.com", identities[0].Issuer)
+	})
+}
+
+func TestList(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	userService := user.NewService(db)
+
+	b, err := userService.Create(user.CreateParams{FullName: "B User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := userService.Create(user.CreateParams{FullName: "A User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := userService.Create(user.CreateParams{FullName: "Deleted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := userService.Delete(deleted.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := userService.List()
+	assert.NoError(t, err)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, a.Id, users[0].Id)
+		assert.Equal(t, b.Id, users[1].Id)
+	}
+}
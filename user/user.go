@@ -6,35 +6,107 @@ import (
 	"slices"
 	"strings"
 	"time"
+
+	"github.com/mattfan00/jvbe/event"
 )
 
 type Service interface {
 	Get(string) (User, error)
-	HandleFromExternal(ExternalUser) (User, error)
+	List() ([]User, error)
+	HandleFromExternal(ExternalUser, Signup) (User, error)
 	Create(CreateParams) (User, error)
 	GetReview(string) (UserReview, error)
 	UpdateReview(UpdateReviewParams) error
 	ListReviews() ([]UserReview, error)
-	ApproveReview(string) error
+	ApproveReview(ReviewParams) error
+	RejectReview(ReviewParams) error
+	Suspend(ReviewParams) error
+	Reapply(string) error
+	ListVouches(string) ([]Vouch, error)
+	ReferralToken(string) (string, error)
+	RequestVouch(RequestVouchParams) (Vouch, error)
+	ConfirmVouch(string, string) error
+	DeleteVouch(string, string) error
+	Delete(string) ([]event.WithdrawnResponse, error)
+	Merge(MergeParams) (MergeResult, error)
+	ListIdentities(string) ([]Identity, error)
+	LinkIdentity(string, ExternalUser) error
+	UnlinkIdentity(string, string, string) error
+	UpdateProfile(UpdateProfileParams) (User, error)
 }
 
 var (
-	ErrNoUser = errors.New("no user found")
+	ErrNoUser               = errors.New("no user found")
+	ErrReviewCommentMissing = errors.New("provide a reason for the decision")
+	ErrInvalidStatus        = errors.New("user does not have the right status for this")
+	ErrReapplyCooldown      = errors.New("you cannot reapply yet")
+)
+
+// Name shown in place of users that deleted their account.
+const DeletedFullName = "Deleted user"
+
+var (
+	// How long a rejected user has to wait before applying again.
+	ReapplyCooldown        = 7 * 24 * time.Hour
+	MaxReviewCommentLength = 300
+)
+
+var (
+	MaxFullNameLength    = 50
+	MaxBioLength         = 300
+	MaxContactInfoLength = 100
 )
 
 type UserStatus int
 
 const (
-	UserStatusActive   UserStatus = iota // has full control of application
-	UserStatusInactive                   // has not been approved yet
+	UserStatusActive    UserStatus = iota // has full control of application
+	UserStatusInactive                    // has not been approved yet
+	UserStatusRejected                    // review was rejected, can reapply after ReapplyCooldown
+	UserStatusSuspended                   // was active until suspended by a reviewer
+	UserStatusDeleted                     // deleted their account, the row is kept anonymized
+	UserStatusMerged                      // duplicate account merged into the user in MergedIntoId
+)
+
+// Who can see the contact info on a user's profile.
+type ContactVisibility int
+
+const (
+	ContactVisibilityHidden       ContactVisibility = iota // only the user
+	ContactVisibilityGroupMembers                          // users sharing a group with the user
+	ContactVisibilityEveryone                              // every user of the application
 )
 
 type User struct {
-	Id         string     `db:"id"`
-	FullName   string     `db:"full_name"`
-	ExternalId string     `db:"external_id"`
-	CreatedAt  time.Time  `db:"created_at"`
-	Status     UserStatus `db:"status"`
+	Id                 string            `db:"id"`
+	FullName           string            `db:"full_name"`
+	ExternalId         string            `db:"external_id"`
+	CreatedAt          time.Time         `db:"created_at"`
+	Status             UserStatus        `db:"status"`
+	Picture            string            `db:"picture"`
+	Bio                string            `db:"bio"`
+	ContactInfo        string            `db:"contact_info"`
+	ContactVisibility  ContactVisibility `db:"contact_visibility"`
+	ExternalFullName   string            `db:"external_full_name"` // name from the IdP, kept in sync on login
+	FullNameOverridden bool              `db:"full_name_overridden"`
+	Timezone           string            `db:"timezone"` // IANA timezone times are shown in, empty for the default
+	MergedIntoId       sql.NullString    `db:"merged_into_id"`
+}
+
+func (u User) IsActive() bool {
+	return u.Status == UserStatusActive
+}
+
+func (u User) IsDeleted() bool {
+	return u.Status == UserStatusDeleted
+}
+
+func (u User) IsMerged() bool {
+	return u.Status == UserStatusMerged
+}
+
+func (u User) IsSuspended() bool {
+	return u.Status == UserStatusSuspended
 }
 
 func (u *User) ToSessionUser() SessionUser {
@@ -42,23 +114,54 @@ func (u *User) ToSessionUser() SessionUser {
 		Id:       u.Id,
 		FullName: u.FullName,
 		Status:   u.Status,
+		Timezone: u.Timezone,
 	}
 }
 
 type UserReview struct {
-	UserId       string         `db:"user_id"`
-	UserFullName string         `db:"user_full_name"`
-	CreatedAt    time.Time      `db:"created_at"`
-	ReviewedAt   sql.NullTime   `db:"reviewed_at"`
-	Comment      sql.NullString `db:"comment"`
-	IsApproved   string         `db:"is_approved"`
+	UserId           string         `db:"user_id"`
+	UserFullName     string         `db:"user_full_name"`
+	UserStatus       UserStatus     `db:"user_status"`
+	CreatedAt        time.Time      `db:"created_at"`
+	ReviewedAt       sql.NullTime   `db:"reviewed_at"`
+	Comment          sql.NullString `db:"comment"`
+	IsApproved       string         `db:"is_approved"`
+	ReviewerId       sql.NullString `db:"reviewer_id"`
+	ReviewerFullName sql.NullString `db:"reviewer_full_name"`
+	ReviewerComment  sql.NullString `db:"reviewer_comment"`
+	ApprovalRule     sql.NullString `db:"approval_rule"`
+	Vouches          []Vouch
+}
+
+func (r UserReview) ConfirmedVouches() int {
+	return countConfirmed(r.Vouches)
+}
+
+func (r UserReview) IsRejected() bool {
+	return r.UserStatus == UserStatusRejected
+}
+
+func (r UserReview) IsSuspended() bool {
+	return r.UserStatus == UserStatusSuspended
+}
+
+// When a rejected user can apply again.
+func (r UserReview) CanReapplyAt() time.Time {
+	return r.ReviewedAt.Time.Add(ReapplyCooldown)
+}
+
+func (r UserReview) CanReapply(now time.Time) bool {
+	return r.IsRejected() && !now.Before(r.CanReapplyAt())
 }
 
 type ExternalUser struct {
-	Id          string `json:"sub"`
-	FullName    string `json:"name"`
-	Picture     string `json:"picture"`
-	Permissions []string
+	Issuer        string `json:"iss"`
+	Id            string `json:"sub"`
+	FullName      string `json:"name"`
+	Picture       string `json:"picture"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Permissions   []string
 }
 
 type SessionUser struct {
@@ -66,6 +169,7 @@ type SessionUser struct {
 	Permissions []string
 	FullName    string
 	Status      UserStatus
+	Timezone    string
 }
 
 func (u SessionUser) IsAuthenticated() bool {
@@ -0,0 +1,271 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+var (
+	ErrNoVoucher         = errors.New("no member found with that name")
+	ErrAmbiguousVoucher  = errors.New("several members have that name, ask them for their referral link instead")
+	ErrNotEnoughVouches  = errors.New("user does not have enough confirmed vouches to be approved")
+	ErrTooManyVouches    = fmt.Errorf("you can name at most %d referrers", MaxVouches)
+	ErrAlreadyVouchedFor = errors.New("you already named this member")
+)
+
+const MaxVouches = 3
+
+// A member confirming they know a user waiting to be reviewed.
+type Vouch struct {
+	UserId          string       `db:"user_id"`
+	VoucherId       string       `db:"voucher_id"`
+	VoucherFullName string       `db:"voucher_full_name"`
+	CreatedAt       time.Time    `db:"created_at"`
+	ConfirmedAt     sql.NullTime `db:"confirmed_at"`
+}
+
+func (v Vouch) IsConfirmed() bool {
+	return v.ConfirmedAt.Valid
+}
+
+func countConfirmed(vouches []Vouch) int {
+	n := 0
+	for _, v := range vouches {
+		if v.IsConfirmed() {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *service) ListVouches(userId string) ([]Vouch, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Vouch{}, err
+	}
+	defer tx.Rollback()
+
+	return listVouches(tx, userId)
+}
+
+type RequestVouchParams struct {
+	UserId      string
+	VoucherName string // full name of an active member
+}
+
+// Names an active member as the user's referrer. The member still has to confirm the vouch.
+func (s *service) RequestVouch(p RequestVouchParams) (Vouch, error) {
+	s.log.Printf("user RequestVouch params %+v", p)
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return Vouch{}, err
+	}
+	defer tx.Rollback()
+
+	u, err := get(tx, p.UserId)
+	if err != nil {
+		return Vouch{}, err
+	}
+	if u.Status != UserStatusInactive {
+		return Vouch{}, ErrInvalidStatus
+	}
+
+	stmt := `
+        SELECT id FROM user
+        WHERE full_name = ? COLLATE NOCASE AND status = ? AND id != ?
+    `
+	var voucherIds []string
+	err = tx.Select(&voucherIds, stmt, strings.TrimSpace(p.VoucherName), UserStatusActive, p.UserId)
+	if err != nil {
+		return Vouch{}, err
+	}
+	if len(voucherIds) == 0 {
+		return Vouch{}, ErrNoVoucher
+	} else if len(voucherIds) > 1 {
+		return Vouch{}, ErrAmbiguousVoucher
+	}
+
+	vouches, err := listVouches(tx, p.UserId)
+	if err != nil {
+		return Vouch{}, err
+	}
+	if len(vouches) >= MaxVouches {
+		return Vouch{}, ErrTooManyVouches
+	}
+	for _, v := range vouches {
+		if v.VoucherId == voucherIds[0] {
+			return Vouch{}, ErrAlreadyVouchedFor
+		}
+	}
+
+	err = createVouch(tx, p.UserId, voucherIds[0], false)
+	if err != nil {
+		return Vouch{}, err
+	}
+
+	v, err := getVouch(tx, p.UserId, voucherIds[0])
+	if err != nil {
+		return Vouch{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Vouch{}, err
+	}
+
+	return v, nil
+}
+
+// Confirms a vouch the user asked the voucher for.
+func (s *service) ConfirmVouch(userId string, voucherId string) error {
+	s.log.Printf("user ConfirmVouch user %s voucher %s", userId, voucherId)
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = getVouch(tx, userId, voucherId)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+        UPDATE user_vouch
+        SET confirmed_at = ?
+        WHERE user_id = ? AND voucher_id = ?
+    `
+	args := []any{time.Now().UTC(), userId, voucherId}
+
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Removes the vouch, whether the voucher declines it or takes back a confirmation.
+func (s *service) DeleteVouch(userId string, voucherId string) error {
+	s.log.Printf("user DeleteVouch user %s voucher %s", userId, voucherId)
+	stmt := `
+        DELETE FROM user_vouch
+        WHERE user_id = ? AND voucher_id = ?
+    `
+	_, err := s.db.Exec(stmt, userId, voucherId)
+	return err
+}
+
+// Token of the user's referral link, created the first time it is needed.
+func (s *service) ReferralToken(userId string) (string, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var token string
+	err = tx.Get(&token, `SELECT token FROM user_referral WHERE user_id = ?`, userId)
+	if err == nil {
+		return token, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	token, err = gonanoid.New()
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `
+        INSERT INTO user_referral (user_id, token, created_at)
+        VALUES (?, ?, ?)
+    `
+	_, err = tx.Exec(stmt, userId, token, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Active member whose referral link has the token.
+func getReferrer(tx *sqlx.Tx, token string) (User, error) {
+	stmt := `
+        SELECT ` + userColumns + ` FROM user
+        WHERE id = (SELECT user_id FROM user_referral WHERE token = ?)
+            AND status = ?
+    `
+	args := []any{token, UserStatusActive}
+
+	var user User
+	err := tx.Get(&user, stmt, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNoUser
+	} else if err != nil {
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+func getVouch(tx *sqlx.Tx, userId string, voucherId string) (Vouch, error) {
+	stmt := `
+        SELECT uv.user_id, uv.voucher_id, uv.created_at, uv.confirmed_at, u.full_name AS voucher_full_name
+        FROM user_vouch AS uv
+        INNER JOIN user AS u ON uv.voucher_id = u.id
+        WHERE uv.user_id = ? AND uv.voucher_id = ?
+    `
+	args := []any{userId, voucherId}
+
+	var v Vouch
+	err := tx.Get(&v, stmt, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Vouch{}, errors.New("no vouch found")
+	}
+	return v, err
+}
+
+func listVouches(tx *sqlx.Tx, userId string) ([]Vouch, error) {
+	stmt := `
+        SELECT uv.user_id, uv.voucher_id, uv.created_at, uv.confirmed_at, u.full_name AS voucher_full_name
+        FROM user_vouch AS uv
+        INNER JOIN user AS u ON uv.voucher_id = u.id
+        WHERE uv.user_id = ?
+        ORDER BY uv.created_at
+    `
+	args := []any{userId}
+
+	vouches := []Vouch{}
+	err := tx.Select(&vouches, stmt, args...)
+	return vouches, err
+}
+
+func createVouch(tx *sqlx.Tx, userId string, voucherId string, confirmed bool) error {
+	now := time.Now().UTC()
+	stmt := `
+        INSERT INTO user_vouch (user_id, voucher_id, created_at, confirmed_at)
+        VALUES (?, ?, ?, ?)
+        ON CONFLICT (user_id, voucher_id) DO NOTHING
+    `
+	args := []any{
+		userId,
+		voucherId,
+		now,
+		sql.NullTime{Time: now, Valid: confirmed},
+	}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
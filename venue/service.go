@@ -0,0 +1,249 @@
+package venue
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/logger"
+)
+
+type service struct {
+	db  *db.DB
+	log logger.Logger
+}
+
+func NewService(db *db.DB) *service {
+	return &service{
+		db:  db,
+		log: logger.NewNoopLogger(),
+	}
+}
+
+func (s *service) SetLogger(l logger.Logger) {
+	s.log = l
+}
+
+func (s *service) Get(id string) (Venue, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return Venue{}, err
+	}
+	defer tx.Rollback()
+
+	v, err := get(tx, id)
+	return v, err
+}
+
+func (s *service) List() ([]Venue, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Venue{}, err
+	}
+	defer tx.Rollback()
+
+	v, err := list(tx)
+	return v, err
+}
+
+type CreateParams struct {
+	Name            string
+	Address         string
+	MapUrl          string
+	Notes           string
+	DefaultCapacity int
+	Timezone        string
+	CreatorId       string
+}
+
+func (s *service) Create(p CreateParams) (string, error) {
+	s.log.Printf("venue Create params %+v", p)
+	if err := validate(p.Name, p.MapUrl, p.DefaultCapacity, p.Timezone); err != nil {
+		return "", err
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	id, err := create(tx, p)
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", err
+	}
+
+	s.log.Printf("created venue %s", id)
+	return id, nil
+}
+
+type UpdateParams struct {
+	Id              string
+	Name            string
+	Address         string
+	MapUrl          string
+	Notes           string
+	DefaultCapacity int
+	Timezone        string
+}
+
+func (s *service) Update(p UpdateParams) error {
+	s.log.Printf("venue Update params %+v", p)
+	if err := validate(p.Name, p.MapUrl, p.DefaultCapacity, p.Timezone); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = update(tx, p)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Soft deletes the venue. Events that already reference the venue keep showing it.
+func (s *service) Delete(id string) error {
+	s.log.Printf("venue Delete id %s", id)
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = delete(tx, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func validate(name string, mapUrl string, defaultCapacity int, timezone string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("venue must have a name")
+	}
+	if defaultCapacity < 0 {
+		return errors.New("default capacity cannot be negative")
+	}
+	if mapUrl != "" {
+		u, err := url.Parse(mapUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.New("map link must be an http(s) URL")
+		}
+	}
+	if _, err := event.LoadLocation(timezone); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func get(tx *sqlx.Tx, id string) (Venue, error) {
+	stmt := `
+        SELECT id, name, address, map_url, notes, default_capacity, timezone, created_at, creator_id
+        FROM venue
+        WHERE id = ? AND is_deleted = FALSE
+    `
+	args := []any{id}
+
+	var v Venue
+	err := tx.Get(&v, stmt, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Venue{}, ErrNoVenue
+	} else if err != nil {
+		return Venue{}, err
+	}
+
+	return v, nil
+}
+
+func list(tx *sqlx.Tx) ([]Venue, error) {
+	stmt := `
+        SELECT id, name, address, map_url, notes, default_capacity, timezone, created_at, creator_id
+        FROM venue
+        WHERE is_deleted = FALSE
+        ORDER BY name ASC
+    `
+
+	var v []Venue
+	err := tx.Select(&v, stmt)
+	return v, err
+}
+
+func create(tx *sqlx.Tx, p CreateParams) (string, error) {
+	id, err := gonanoid.New()
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `
+        INSERT INTO venue (id, name, address, map_url, notes, default_capacity, timezone, created_at, creator_id)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `
+	args := []any{
+		id,
+		strings.TrimSpace(p.Name),
+		p.Address,
+		p.MapUrl,
+		p.Notes,
+		p.DefaultCapacity,
+		p.Timezone,
+		db.Now(),
+		p.CreatorId,
+	}
+
+	_, err = tx.Exec(stmt, args...)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func update(tx *sqlx.Tx, p UpdateParams) error {
+	stmt := `
+        UPDATE venue
+        SET name = ?, address = ?, map_url = ?, notes = ?, default_capacity = ?, timezone = ?
+        WHERE id = ?
+    `
+	args := []any{
+		strings.TrimSpace(p.Name),
+		p.Address,
+		p.MapUrl,
+		p.Notes,
+		p.DefaultCapacity,
+		p.Timezone,
+		p.Id,
+	}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
+
+func delete(tx *sqlx.Tx, id string) error {
+	stmt := `
+        UPDATE venue
+        SET is_deleted = TRUE
+        WHERE id = ?
+    `
+	args := []any{id}
+
+	_, err := tx.Exec(stmt, args...)
+	return err
+}
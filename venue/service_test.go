@@ -0,0 +1,91 @@
+package venue_test
+
+import (
+	"testing"
+
+	"github.com/mattfan00/jvbe/db"
+	"github.com/mattfan00/jvbe/event"
+	"github.com/mattfan00/jvbe/user"
+	"github.com/mattfan00/jvbe/venue"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreate(t *testing.T) {
+	t.Run("NameRequiredError", func(t *testing.T) {
+		_, err := venue.NewService(nil).Create(venue.CreateParams{Name: " "})
+		assert.Error(t, err)
+	})
+
+	t.Run("InvalidMapUrlError", func(t *testing.T) {
+		_, err := venue.NewService(nil).Create(venue.CreateParams{Name: "gym", MapUrl: "javascript:alert(1)"})
+		assert.Error(t, err)
+	})
+
+	t.Run("InvalidTimezoneError", func(t *testing.T) {
+		_, err := venue.NewService(nil).Create(venue.CreateParams{Name: "gym", Timezone: "Nowhere/City"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Ok", func(t *testing.T) {
+		db := db.TestingConnect(t)
+		defer db.Close()
+		venueService := venue.NewService(db)
+
+		id, err := venueService.Create(venue.CreateParams{
+			Name:            "gym",
+			MapUrl:          "https://maps.example.com",
+			DefaultCapacity: 12,
+			Timezone:        "America/Chicago",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		v, err := venueService.Get(id)
+		assert.NoError(t, err)
+		assert.Equal(t, "gym", v.Name)
+		assert.Equal(t, 12, v.DefaultCapacity)
+		assert.Equal(t, "America/Chicago", v.Timezone)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	db := db.TestingConnect(t)
+	defer db.Close()
+	venueService := venue.NewService(db)
+	eventService := event.NewService(db)
+	userService := user.NewService(db)
+
+	u, err := userService.Create(user.CreateParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := venueService.Create(venue.CreateParams{Name: "gym"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventId, err := eventService.Create(event.CreateParams{CreatorId: u.Id, VenueId: id, Location: "fallback"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = venueService.Delete(id)
+	assert.NoError(t, err)
+
+	venues, err := venueService.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(venues))
+
+	// events already at the venue keep showing it
+	e, err := eventService.Get(eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "gym", e.DisplayLocation())
+}
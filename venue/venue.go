@@ -0,0 +1,31 @@
+package venue
+
+import (
+	"errors"
+	"time"
+)
+
+type Service interface {
+	Get(string) (Venue, error)
+	List() ([]Venue, error)
+	Create(CreateParams) (string, error)
+	Update(UpdateParams) error
+	Delete(string) error
+}
+
+type Venue struct {
+	Id              string    `db:"id"`
+	Name            string    `db:"name"`
+	Address         string    `db:"address"`
+	MapUrl          string    `db:"map_url"`
+	Notes           string    `db:"notes"`
+	DefaultCapacity int       `db:"default_capacity"`
+	Timezone        string    `db:"timezone"` // IANA timezone, used as the default for events at the venue
+	CreatedAt       time.Time `db:"created_at"`
+	CreatorId       string    `db:"creator_id"`
+	IsDeleted       bool      `db:"is_deleted"`
+}
+
+var (
+	ErrNoVenue = errors.New("no venue found")
+)